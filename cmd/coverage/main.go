@@ -0,0 +1,65 @@
+// runs a corpus of emails through the parser registry and reports coverage per bank
+// and parser, with the unmatched emails grouped by sender domain and subject template
+//
+//	go run ./cmd/coverage [-json] [-parsers dir] [path...]
+//
+// paths are .eml files or directories searched for them, internal/email by default.
+// a capture directory of the server (CAPTURE_DIR) makes a good corpus
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+
+	"null-email-parser/internal/coverage"
+	"null-email-parser/internal/declarative"
+	_ "null-email-parser/internal/email/all"
+	"null-email-parser/internal/script"
+
+	"github.com/charmbracelet/log"
+)
+
+func main() {
+	logger := log.NewWithOptions(os.Stderr, log.Options{
+		ReportTimestamp: true,
+		Level:           log.InfoLevel,
+		Prefix:          "coverage",
+	})
+
+	asJSON := flag.Bool("json", false, "print the report as json")
+	parsersDir := flag.String("parsers", "", "directory of yaml and script parsers to load, like PARSERS_DIR")
+	flag.Parse()
+
+	if *parsersDir != "" {
+		if _, err := declarative.Reload(*parsersDir); err != nil {
+			logger.Fatal("invalid parser definitions", "err", err)
+		}
+		if _, err := script.Reload(*parsersDir, script.DefaultLimits, logger); err != nil {
+			logger.Fatal("invalid parser scripts", "err", err)
+		}
+	}
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"internal/email"}
+	}
+
+	report, err := coverage.Scan(paths...)
+	if err != nil {
+		logger.Fatal("scan failed", "err", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		err = report.WriteText(os.Stdout)
+	}
+	if err != nil && !errors.Is(err, os.ErrClosed) {
+		logger.Fatal("writing report failed", "err", err)
+	}
+}
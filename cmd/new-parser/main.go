@@ -0,0 +1,269 @@
+// scaffolds a parser package for a new bank from sample emails
+//
+//	go run ./cmd/new-parser -bank mybank samples/*.eml
+//
+// creates internal/email/<bank> with one skeleton parser per distinct subject, the
+// samples anonymized into testdata, golden files to fill in and a golden test, and
+// registers the package in internal/email/all. run it from the repository root
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"null-email-parser/internal/fixture"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	modulePath = "null-email-parser"
+	emailDir   = "internal/email"
+	allFile    = "internal/email/all/all.go"
+)
+
+var bankName = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+func main() {
+	logger := log.NewWithOptions(os.Stderr, log.Options{
+		ReportTimestamp: true,
+		Level:           log.InfoLevel,
+		Prefix:          "new-parser",
+	})
+
+	bank := flag.String("bank", "", "bank name, a lowercase go package name such as \"td\"")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: new-parser -bank <name> sample.eml...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if !bankName.MatchString(*bank) || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(logger, *bank, flag.Args()); err != nil {
+		logger.Fatal("scaffolding failed", "err", err)
+	}
+}
+
+// emailType is a distinct subject among the samples, one parser each
+type emailType struct {
+	Bank     string
+	Type     string // go type name
+	File     string // go file name
+	Subject  string
+	From     string
+	Fixtures []string // fixture file names
+}
+
+func run(logger *log.Logger, bank string, samples []string) error {
+	pkgDir := filepath.Join(emailDir, bank)
+	testdata := filepath.Join(pkgDir, "testdata")
+	if err := os.MkdirAll(testdata, 0755); err != nil {
+		return err
+	}
+
+	var types []*emailType
+	for _, sample := range samples {
+		data, err := os.ReadFile(sample)
+		if err != nil {
+			return err
+		}
+		f, err := fixture.Decode(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", sample, err)
+		}
+
+		path, err := f.Write(testdata)
+		if err != nil {
+			return err
+		}
+		logger.Info("wrote fixture", "sample", sample, "fixture", path)
+
+		i := slices.IndexFunc(types, func(t *emailType) bool { return t.Subject == f.Subject })
+		if i < 0 {
+			types = append(types, &emailType{Bank: bank, Subject: f.Subject, From: f.From})
+			i = len(types) - 1
+		}
+		types[i].Fixtures = append(types[i].Fixtures, filepath.Base(path))
+	}
+
+	taken := map[string]bool{}
+	for _, t := range types {
+		t.Type = typeName(t.Subject, taken)
+		t.File = fileName(t.Type)
+
+		path := filepath.Join(pkgDir, t.File)
+		if _, err := os.Stat(path); err == nil {
+			logger.Warn("parser file exists, leaving it alone", "file", path, "subject", t.Subject)
+		} else if err := render(path, parserTemplate, t); err != nil {
+			return err
+		}
+
+		for _, name := range t.Fixtures {
+			if err := fixture.WriteExpected(filepath.Join(testdata, name), fixture.Placeholder(t.Bank+"."+t.Type, t.Bank)); err != nil {
+				return err
+			}
+		}
+		logger.Info("scaffolded parser", "type", t.Type, "subject", t.Subject, "fixtures", len(t.Fixtures))
+	}
+
+	goldenTest := filepath.Join(pkgDir, "golden_test.go")
+	if _, err := os.Stat(goldenTest); os.IsNotExist(err) {
+		if err := render(goldenTest, goldenTemplate, struct{ Bank string }{bank}); err != nil {
+			return err
+		}
+	}
+
+	if err := registerPackage(bank); err != nil {
+		return fmt.Errorf("registering in %s: %w", allFile, err)
+	}
+
+	logger.Info("done, fill in the TODOs and the expected.json files, then run the tests", "package", pkgDir)
+	return nil
+}
+
+var nonWord = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// typeName turns a subject into an unexported go type name, e.g. "You made a
+// purchase." becomes "youMadeAPurchase". names in taken are numbered
+func typeName(subject string, taken map[string]bool) string {
+	words := strings.Fields(nonWord.ReplaceAllString(subject, " "))
+	if len(words) > 5 {
+		words = words[:5]
+	}
+
+	var b strings.Builder
+	for i, w := range words {
+		w = strings.ToLower(w)
+		if i > 0 {
+			w = strings.ToUpper(w[:1]) + w[1:]
+		}
+		b.WriteString(w)
+	}
+
+	name := b.String()
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "email" + strings.ToUpper(name[:min(1, len(name))]) + name[min(1, len(name)):]
+	}
+
+	base := name
+	for i := 2; taken[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	taken[name] = true
+	return name
+}
+
+var upper = regexp.MustCompile(`[A-Z]`)
+
+// fileName turns a type name into a file name, e.g. "youMadeAPurchase" becomes
+// "you_made_a_purchase.go"
+func fileName(typ string) string {
+	return upper.ReplaceAllStringFunc(typ, func(s string) string { return "_" + strings.ToLower(s) }) + ".go"
+}
+
+func render(path string, tmpl *template.Template, data any) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting %s: %w", path, err)
+	}
+	return os.WriteFile(path, src, 0644)
+}
+
+// registerPackage adds the blank import of the bank to internal/email/all
+func registerPackage(bank string) error {
+	src, err := os.ReadFile(allFile)
+	if err != nil {
+		return err
+	}
+
+	line := fmt.Sprintf("\t_ %q\n", modulePath+"/"+emailDir+"/"+bank)
+	if bytes.Contains(src, []byte(strings.TrimSpace(line))) {
+		return nil
+	}
+
+	marker := []byte("\t// new parsers should be added here")
+	i := bytes.Index(src, marker)
+	if i < 0 {
+		return fmt.Errorf("marker comment %q not found", strings.TrimSpace(string(marker)))
+	}
+
+	out := slices.Concat(src[:i], []byte(line), src[i:])
+	if out, err = format.Source(out); err != nil {
+		return err
+	}
+	return os.WriteFile(allFile, out, 0644)
+}
+
+var parserTemplate = template.Must(template.New("parser").Parse(`package {{.Bank}}
+
+import (
+	"regexp"
+	"strings"
+
+	"null-email-parser/internal/domain"
+	"null-email-parser/internal/parser"
+)
+
+func init() { parser.Register(&{{.Type}}{}) }
+
+// {{.Type}} handles {{printf "%q" .Subject}} emails{{with .From}} from {{.}}{{end}}
+type {{.Type}} struct{}
+
+func (p *{{.Type}}) Match(m parser.EmailMeta) bool {
+	return strings.Contains(m.Subject, {{printf "%q" .Subject}})
+}
+
+func (p *{{.Type}}) Parse(m parser.EmailMeta) (*domain.Transaction, error) {
+	// TODO: write a regex with one capture group per field, see{{range .Fixtures}} testdata/{{.}}{{end}}
+	patterns := map[string]*regexp.Regexp{
+		"account": regexp.MustCompile(` + "`TODO`" + `),
+		"amount":  regexp.MustCompile(` + "`(` + parser.MoneyPattern + `)`" + `), // first amount in the text
+		"txdate":  regexp.MustCompile(` + "`(` + parser.DatePattern + `)`" + `), // first date in the text
+		"desc":    regexp.MustCompile(` + "`TODO`" + `),
+	}
+	fields, err := parser.ExtractFields(m.Text, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	return parser.BuildTransaction(
+		m,
+		fields,
+		{{printf "%q" .Bank}},
+		"CAD", // TODO: currency of amounts that do not name theirs, e.g. "$12.00"
+		domain.Out, // TODO: direction
+		strings.TrimSpace(fields.Get("desc")),
+	)
+}
+`))
+
+var goldenTemplate = template.Must(template.New("golden").Parse(`package {{.Bank}}_test
+
+import (
+	"testing"
+
+	_ "null-email-parser/internal/email/all"
+	"null-email-parser/internal/parser/parsertest"
+)
+
+func TestGolden(t *testing.T) {
+	parsertest.Run(t, "testdata")
+}
+`))
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// the scaffolded package must build, so its golden test fails on the TODOs rather
+// than on a missing import. it is compiled in place through an overlay, without
+// writing to the tree
+func TestTemplatesCompile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the go tool")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const bank = "scaffoldcheck"
+	pkgDir := filepath.Join(root, emailDir, bank)
+	if _, err := os.Stat(pkgDir); err == nil {
+		t.Fatalf("%s exists", pkgDir)
+	}
+
+	tmp := t.TempDir()
+	taken := map[string]bool{}
+	types := []*emailType{
+		{Bank: bank, Subject: "You made a purchase.", From: "alerts@example.com", Fixtures: []string{"you-made-a-purchase.decoded.eml"}},
+		{Bank: bank, Subject: "2 deposits \"pending\"", Fixtures: []string{"deposits.decoded.eml"}},
+	}
+	replace := map[string]string{}
+	for _, typ := range types {
+		typ.Type = typeName(typ.Subject, taken)
+		typ.File = fileName(typ.Type)
+		path := filepath.Join(tmp, typ.File)
+		if err := render(path, parserTemplate, typ); err != nil {
+			t.Fatalf("rendering %s: %v", typ.File, err)
+		}
+		replace[filepath.Join(pkgDir, typ.File)] = path
+	}
+	golden := filepath.Join(tmp, "golden_test.go")
+	if err := render(golden, goldenTemplate, struct{ Bank string }{bank}); err != nil {
+		t.Fatalf("rendering golden_test.go: %v", err)
+	}
+	replace[filepath.Join(pkgDir, "golden_test.go")] = golden
+
+	overlay, err := json.Marshal(map[string]any{"Replace": replace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overlayPath := filepath.Join(tmp, "overlay.json")
+	if err := os.WriteFile(overlayPath, overlay, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// vet needs the directory on disk, compiling the test binary does not
+	cmd := exec.Command(goTool, "test", "-vet=off", "-c", "-o", filepath.Join(tmp, "scaffold.test"), "-overlay", overlayPath, "./"+emailDir+"/"+bank)
+	cmd.Dir = root
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("compiling the scaffolded package: %v\n%s", err, out)
+	}
+}
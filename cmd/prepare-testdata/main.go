@@ -1,16 +1,21 @@
 // processes .eml files in testdata directories, extracts content and anonymizes sensitive data
 // no arguments needed, it finds all testdata dirs under ./internal/email
+//
+//	-check           report likely personal data left in committed fixtures, exit 1 if any
+//	-fix             anonymize the committed fixtures again, in place
+//	-detectors list  comma separated detectors to apply, see fixture.Detectors
+//	-salt string     seed of the fake values, change it to get different fakes
 
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 
-	"null-email-parser/internal/email"
+	"null-email-parser/internal/fixture"
 
 	"github.com/charmbracelet/log"
 )
@@ -23,14 +28,43 @@ func main() {
 		Prefix:          "prepare-testdata",
 	})
 
+	check := flag.Bool("check", false, "report likely personal data in committed fixtures and exit 1 if any")
+	fix := flag.Bool("fix", false, "anonymize committed fixtures again, in place")
+	detectors := flag.String("detectors", strings.Join(fixture.DefaultDetectors, ","), "comma separated detectors to apply")
+	salt := flag.String("salt", fixture.DefaultSalt, "seed of the fake values")
+	flag.Parse()
+
+	anonymizer, err := fixture.NewAnonymizer(strings.Split(*detectors, ","), *salt)
+	if err != nil {
+		logger.Fatal("Invalid detectors", "error", err)
+	}
+
 	testDataDirs, err := findTestDataDirs("./internal/email")
 	if err != nil {
 		logger.Fatal("Failed to find testdata directories", "error", err)
 	}
 
+	if *check {
+		if findings := checkFixtures(logger, anonymizer, testDataDirs); findings > 0 {
+			logger.Error("Fixtures contain likely personal data, run with -fix", "findings", findings)
+			os.Exit(1)
+		}
+		logger.Info("No personal data found")
+		return
+	}
+
+	if *fix {
+		for _, dir := range testDataDirs {
+			if err := fixFixtures(logger, anonymizer, dir); err != nil {
+				logger.Error("Failed to fix directory", "dir", dir, "error", err)
+			}
+		}
+		return
+	}
+
 	totalProcessed := 0
 	for _, dir := range testDataDirs {
-		processed, err := processTestDataDir(logger, dir)
+		processed, err := processTestDataDir(logger, anonymizer, dir)
 		if err != nil {
 			logger.Error("Failed to process directory", "dir", dir, "error", err)
 			continue
@@ -57,7 +91,7 @@ func findTestDataDirs(root string) ([]string, error) {
 	return dirs, err
 }
 
-func processTestDataDir(logger *log.Logger, dir string) (int, error) {
+func processTestDataDir(logger *log.Logger, anonymizer *fixture.Anonymizer, dir string) (int, error) {
 	files, err := os.ReadDir(dir)
 	if err != nil {
 		return 0, err
@@ -70,7 +104,7 @@ func processTestDataDir(logger *log.Logger, dir string) (int, error) {
 		}
 
 		filename := file.Name()
-		isAlreadyDecoded := strings.HasSuffix(filename, ".decoded.eml")
+		isAlreadyDecoded := strings.HasSuffix(filename, fixture.Suffix)
 		isRawEmailFile := strings.HasSuffix(filename, ".eml") && !isAlreadyDecoded
 
 		if !isRawEmailFile {
@@ -78,7 +112,7 @@ func processTestDataDir(logger *log.Logger, dir string) (int, error) {
 		}
 
 		filePath := filepath.Join(dir, filename)
-		if err := processEmailFile(filePath); err != nil {
+		if err := processEmailFile(anonymizer, filePath); err != nil {
 			logger.Error("Failed to process", "file", filePath, "error", err)
 			continue
 		}
@@ -89,120 +123,70 @@ func processTestDataDir(logger *log.Logger, dir string) (int, error) {
 	return processed, nil
 }
 
-func processEmailFile(filePath string) error {
+func processEmailFile(anonymizer *fixture.Anonymizer, filePath string) error {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return err
 	}
 
-	msg, content, err := email.ParseMessage(data)
+	f, err := anonymizer.Decode(data)
 	if err != nil {
-		return fmt.Errorf("failed to parse email - cannot extract subject: %w", err)
-	}
-
-	subject := msg.Header.Get("Subject")
-	if subject == "" {
-		subject = "no-subject"
+		return err
 	}
 
-	decodedPath := createDecodedPath(filePath, subject)
-
-	decodedEmail := fmt.Sprintf(`Subject: %s
-From: %s
-To: %s
-Date: %s
-
-%s`,
-		subject,
-		anonymizeEmail(msg.Header.Get("From")),
-		anonymizeEmail(msg.Header.Get("To")),
-		msg.Header.Get("Date"),
-		anonymizeContent(content))
-
-	return os.WriteFile(decodedPath, []byte(decodedEmail), 0644)
+	_, err = f.Write(filepath.Dir(filePath))
+	return err
 }
 
-func createDecodedPath(originalPath, subject string) string {
-	dir := filepath.Dir(originalPath)
-	cleanSubject := sanitizeSubject(subject)
-
-	baseFilename := cleanSubject + ".decoded.eml"
-	decodedPath := filepath.Join(dir, baseFilename)
-
-	if _, err := os.Stat(decodedPath); os.IsNotExist(err) {
-		return decodedPath
-	}
-
-	counter := 1
-	for {
-		numberedFilename := fmt.Sprintf("%s-%d.decoded.eml", cleanSubject, counter)
-		numberedPath := filepath.Join(dir, numberedFilename)
-
-		if _, err := os.Stat(numberedPath); os.IsNotExist(err) {
-			return numberedPath
-		}
-
-		counter++
-	}
+func decodedFixtures(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "*"+fixture.Suffix))
 }
 
-func sanitizeSubject(subject string) string {
-	clean := strings.ToLower(subject)
-	clean = strings.ReplaceAll(clean, " ", "-")
-
-	var result strings.Builder
-	for _, r := range clean {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
-			result.WriteRune(r)
+// checkFixtures logs every finding in the decoded fixtures and returns their count
+func checkFixtures(logger *log.Logger, anonymizer *fixture.Anonymizer, dirs []string) int {
+	total := 0
+	for _, dir := range dirs {
+		files, err := decodedFixtures(dir)
+		if err != nil {
+			logger.Error("Failed to list fixtures", "dir", dir, "error", err)
+			total++
+			continue
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				logger.Error("Failed to read", "file", file, "error", err)
+				total++
+				continue
+			}
+			for _, f := range anonymizer.Find(string(data)) {
+				logger.Warn("Likely personal data", "file", fmt.Sprintf("%s:%d", file, f.Line), "detector", f.Detector, "value", f.Value)
+				total++
+			}
 		}
 	}
-
-	clean = result.String()
-
-	for strings.Contains(clean, "--") {
-		clean = strings.ReplaceAll(clean, "--", "-")
-	}
-	clean = strings.Trim(clean, "-")
-
-	if clean == "" {
-		clean = "no-subject"
-	}
-
-	return clean
+	return total
 }
 
-func anonymizeEmail(header string) string {
-	if header == "" {
-		return ""
-	}
-
-	nameEmailPattern := regexp.MustCompile(`^(.+?)\s*<([^>]+)>$`)
-	emailOnlyPattern := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
-
-	if nameEmailPattern.MatchString(header) {
-		return "Example <email@example.com>"
+// fixFixtures anonymizes the decoded fixtures of dir in place
+func fixFixtures(logger *log.Logger, anonymizer *fixture.Anonymizer, dir string) error {
+	files, err := decodedFixtures(dir)
+	if err != nil {
+		return err
 	}
-
-	if emailOnlyPattern.MatchString(strings.TrimSpace(header)) {
-		return "email@example.com"
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		fixed := anonymizer.Anonymize(string(data))
+		if fixed == string(data) {
+			continue
+		}
+		if err := os.WriteFile(file, []byte(fixed), 0644); err != nil {
+			return err
+		}
+		logger.Info("Anonymized", "file", file)
 	}
-
-	emailPattern := regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
-	return emailPattern.ReplaceAllString(header, "email@example.com")
-}
-
-func anonymizeContent(content string) string {
-	httpPattern := regexp.MustCompile(`https?://[^\s\)>\]]+`)
-	content = httpPattern.ReplaceAllString(content, "https://example.com")
-
-	fromPattern := regexp.MustCompile(`From:\s*(.+?)\s*<([^>]+)>`)
-	content = fromPattern.ReplaceAllString(content, "From: Example <email@example.com>")
-
-	toQuotedPattern := regexp.MustCompile(`To:\s*"[^"]*"\s*<[^>]+>`)
-	content = toQuotedPattern.ReplaceAllString(content, "To: Example <email@example.com>")
-
-	toPattern := regexp.MustCompile(`To:\s*([^<\s]+)\s*<([^>]+)>`)
-	content = toPattern.ReplaceAllString(content, "To: Example <email@example.com>")
-
-	return content
+	return nil
 }
@@ -0,0 +1,117 @@
+// moves emails captured by the server (CAPTURE_DIR) into the testdata of the bank
+// whose parser matched them, with a golden file of TODOs next to each
+//
+//	go run ./cmd/pull-captures -from /path/to/captures
+//
+// captures without a known bank go to internal/email/unsorted/testdata. the capture
+// directory is usually a volume shared with the container, or a copy of it made with
+// rsync or scp. run it from the repository root
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"null-email-parser/internal/capture"
+	"null-email-parser/internal/fixture"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	emailDir    = "internal/email"
+	unsortedDir = "internal/email/unsorted/testdata"
+)
+
+func main() {
+	logger := log.NewWithOptions(os.Stderr, log.Options{
+		ReportTimestamp: true,
+		Level:           log.InfoLevel,
+		Prefix:          "pull-captures",
+	})
+
+	from := flag.String("from", "", "capture directory of the server")
+	keep := flag.Bool("keep", false, "leave the captures in place instead of removing them")
+	flag.Parse()
+
+	if *from == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(logger, *from, *keep); err != nil {
+		logger.Fatal("pulling captures failed", "err", err)
+	}
+}
+
+func run(logger *log.Logger, from string, keep bool) error {
+	captures, err := capture.List(from)
+	if err != nil {
+		return err
+	}
+	if len(captures) == 0 {
+		logger.Info("no captures found", "dir", from)
+		return nil
+	}
+
+	for _, c := range captures {
+		path, err := pull(c)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Path, err)
+		}
+		logger.Info("pulled capture", "outcome", c.Meta.Outcome, "parser", c.Meta.Parser, "fixture", path)
+
+		if keep {
+			continue
+		}
+		if err := os.Remove(c.Path); err != nil {
+			return err
+		}
+		if err := os.Remove(capture.MetaPath(c.Path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	logger.Info("done, fill in the expected.json files and run the tests", "count", len(captures))
+	return nil
+}
+
+// pull writes a capture to its testdata directory with a placeholder golden file
+func pull(c capture.Capture) (string, error) {
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return "", err
+	}
+
+	dir := testdataDir(c.Meta.Bank)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSuffix(filepath.Base(c.Path), fixture.Suffix)
+	name = strings.TrimPrefix(name, string(c.Meta.Outcome)+"-")
+	f := fixture.Fixture{Name: name, Data: data} // anonymized already, when it was recorded
+	path, err := f.Write(dir)
+	if err != nil {
+		return "", err
+	}
+
+	return path, fixture.WriteExpected(path, fixture.Placeholder(c.Meta.Parser, c.Meta.Bank))
+}
+
+// testdataDir is the testdata of the bank's package, or the unsorted directory when
+// the bank has none
+func testdataDir(bank string) string {
+	if bank == "" || strings.ContainsAny(bank, `/\.`) {
+		return unsortedDir
+	}
+	pkg := filepath.Join(emailDir, bank)
+	if info, err := os.Stat(pkg); err != nil || !info.IsDir() {
+		return unsortedDir
+	}
+	return filepath.Join(pkg, "testdata")
+}
@@ -2,17 +2,28 @@ package main
 
 import (
 	"context"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"null-email-parser/internal/admin"
 	"null-email-parser/internal/api"
+	"null-email-parser/internal/capture"
 	"null-email-parser/internal/config"
+	"null-email-parser/internal/coverage"
+	"null-email-parser/internal/declarative"
+	"null-email-parser/internal/drift"
 	"null-email-parser/internal/grpc"
+	"null-email-parser/internal/heuristic"
+	"null-email-parser/internal/pending"
+	"null-email-parser/internal/script"
 	"null-email-parser/internal/smtp"
 	"null-email-parser/internal/version"
 
 	"github.com/charmbracelet/log"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func main() {
@@ -50,8 +61,41 @@ func main() {
 	}
 	logger.Info("null-core connectivity confirmed")
 
+	// ----- runtime parsers --------
+	if cfg.ParsersDir != "" {
+		loadParsers(logger, cfg.ParsersDir)
+	}
+
 	// ----- services ---------------
 	handler := smtp.NewEmailHandler(apiClient, logger, cfg.UnsafeSaveEML)
+	if len(cfg.HeuristicSenders) > 0 {
+		handler.Fallback = heuristic.New(cfg.HeuristicSenders, cfg.HeuristicCurrency)
+		if cfg.HeuristicReviewDir != "" {
+			handler.Review = &heuristic.Review{Dir: cfg.HeuristicReviewDir}
+		}
+		logger.Info("heuristic extractor enabled", "senders", cfg.HeuristicSenders, "currency", cfg.HeuristicCurrency, "review_dir", cfg.HeuristicReviewDir)
+	}
+	if cfg.CaptureDir != "" {
+		outcomes, err := capture.ParseOutcomes(cfg.CaptureOutcomes)
+		if err != nil {
+			logger.Fatal("invalid CAPTURE", "err", err)
+		}
+		handler.Capture = &capture.Recorder{Dir: cfg.CaptureDir, Outcomes: outcomes, Limit: cfg.CaptureLimit}
+		logger.Info("capturing unhandled emails as anonymized fixtures", "dir", cfg.CaptureDir, "outcomes", cfg.CaptureOutcomes, "limit", cfg.CaptureLimit)
+	}
+	var pendingStore *pending.Store
+	if cfg.PendingState != "" {
+		pendingStore, err = pending.New(cfg.PendingState)
+		if err != nil {
+			logger.Fatal("pending state", "err", err)
+		}
+		pendingStore.OnHold = func(e pending.Entry) { notifyPending(logger, cfg.PendingWebhook, e) }
+		handler.Pending = pendingStore
+		logger.Info("holding transactions of unknown accounts for confirmation", "state", cfg.PendingState, "pending", len(pendingStore.List("")))
+		if cfg.AdminAddress == "" {
+			logger.Warn("ACCOUNT_MODE=confirm without ADMIN_PORT, held transactions can't be released")
+		}
+	}
 	smtpServer := smtp.NewServer(cfg.SMTPAddress, cfg.Domain, handler)
 	if cfg.TLSCert != "" && cfg.TLSKey != "" {
 		smtpServer = smtpServer.WithTLS(cfg.TLSCert, cfg.TLSKey, cfg.TLSRequired)
@@ -62,6 +106,22 @@ func main() {
 		logger.Fatal("grpc health server init", "err", err)
 	}
 
+	// ----- drift detection --------
+	tracker, err := drift.New(cfg.DriftState)
+	if err != nil {
+		logger.Fatal("drift state", "err", err)
+	}
+	tracker.OnChange = func(a drift.Alert, drifted int) { setDriftStatus(grpcHealthSrv, a, drifted) }
+	alerts := tracker.Alerts()
+	for _, a := range alerts {
+		logger.Warn("parser template drift detected", "parser", a.Parser, "version", a.Version, "reason", a.Reason, "since", a.Since)
+		setDriftStatus(grpcHealthSrv, a, len(alerts))
+	}
+	if len(alerts) == 0 {
+		grpcHealthSrv.SetStatus("drift", grpc_health_v1.HealthCheckResponse_SERVING)
+	}
+	handler.Drift = tracker
+
 	// ----- servers ----------------
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -73,12 +133,59 @@ func main() {
 		}
 	}()
 
+	if cfg.MetricsAddress != "" {
+		go func() {
+			logger.Info("metrics server starting", "address", cfg.MetricsAddress, "path", "/debug/vars")
+			if err := http.ListenAndServe(cfg.MetricsAddress, nil); err != nil {
+				logger.Error("metrics server error", "err", err)
+			}
+		}()
+	}
+
+	if cfg.AdminAddress != "" {
+		if cfg.AdminKey == "" {
+			logger.Fatal("ADMIN_PORT is set without ADMIN_KEY")
+		}
+		mux := http.NewServeMux()
+		tracker.Register(mux)
+		if pendingStore != nil {
+			(&pending.Admin{
+				Store: pendingStore,
+				Core:  apiClient,
+				Log:   logger.WithPrefix("admin"),
+				Alias: smtp.AccountAlias,
+			}).Register(mux)
+		}
+		go func() {
+			logger.Info("admin server starting", "address", cfg.AdminAddress)
+			if err := http.ListenAndServe(cfg.AdminAddress, admin.RequireKey(cfg.AdminKey, mux)); err != nil {
+				logger.Error("admin server error", "err", err)
+			}
+		}()
+	}
+
+	if cfg.CaptureDir != "" && cfg.CoverageInterval > 0 {
+		go reportCoverage(ctx, logger, cfg.CaptureDir, cfg.CoverageInterval)
+	}
+
 	go func() {
 		if err := smtpServer.Start(ctx); err != nil {
 			logger.Fatal("smtp server error", "err", err)
 		}
 	}()
 
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if cfg.ParsersDir == "" {
+				logger.Warn("received SIGHUP but PARSERS_DIR is not set, nothing to reload")
+				continue
+			}
+			loadParsers(logger, cfg.ParsersDir)
+		}
+	}()
+
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	<-stop
@@ -87,3 +194,88 @@ func main() {
 	cancel()
 	grpcHealthSrv.Stop()
 }
+
+// loadParsers (re)loads the declarative and script parsers, logging every invalid one
+func loadParsers(logger *log.Logger, dir string) {
+	count, err := declarative.Reload(dir)
+	if err != nil {
+		for _, e := range unjoin(err) {
+			logger.Error("invalid parser definition", "err", e)
+		}
+	}
+	logger.Info("loaded declarative parsers", "dir", dir, "count", count)
+
+	count, err = script.Reload(dir, script.DefaultLimits, logger)
+	if err != nil {
+		for _, e := range unjoin(err) {
+			logger.Error("invalid parser script", "err", e)
+		}
+	}
+	logger.Info("loaded parser scripts", "dir", dir, "count", count)
+}
+
+// setDriftStatus reports a drifted parser as the "drift/<id>" health service and
+// whether any parser drifted as "drift". the overall status is left serving, a
+// drifted template is no reason to restart the service
+func setDriftStatus(srv *grpc.HealthServer, a drift.Alert, drifted int) {
+	status := grpc_health_v1.HealthCheckResponse_SERVING
+	if a.Drifted() {
+		status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+	srv.SetStatus("drift/"+a.Parser, status)
+
+	overall := grpc_health_v1.HealthCheckResponse_SERVING
+	if drifted > 0 {
+		overall = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+	}
+	srv.SetStatus("drift", overall)
+}
+
+// reportCoverage periodically reruns the captured emails through the registry and
+// logs the unmatched clusters, so parsers added or reloaded since are accounted for
+func reportCoverage(ctx context.Context, logger *log.Logger, dir string, interval time.Duration) {
+	logger = logger.WithPrefix("coverage")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := coverage.Scan(dir)
+		if err != nil {
+			logger.Error("coverage scan failed", "dir", dir, "err", err)
+			continue
+		}
+		logger.Info("captured email coverage", "emails", report.Total, "parsed", report.Parsed, "failed", report.Failed, "unmatched", report.Unmatched, "ambiguous", report.Ambiguous)
+		for _, c := range report.Clusters {
+			logger.Info("unmatched email cluster", "domain", c.Domain, "subject", c.Subject, "count", c.Count, "variants", c.Variants)
+		}
+	}
+}
+
+// notifyPending tells the user that transactions of an unknown account are held, in
+// the log and through the webhook when one is set. it is called under the store's lock,
+// so the webhook is posted in the background
+func notifyPending(logger *log.Logger, webhook string, e pending.Entry) {
+	logger.Warn("transactions held for an unknown account, map or approve it through the admin api", "user_uuid", e.UserUUID, "bank", e.Account.Bank, "account", e.Account.Name, "kind", e.Account.Kind, "pending_id", e.ID)
+	if webhook == "" {
+		return
+	}
+	go func() {
+		if err := (&pending.Webhook{URL: webhook}).Notify(e); err != nil {
+			logger.Error("failed to notify about pending account", "pending_id", e.ID, "err", err)
+		}
+	}()
+}
+
+// unjoin splits an errors.Join error into its parts
+func unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
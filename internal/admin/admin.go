@@ -0,0 +1,24 @@
+// Package admin guards the admin api, the http endpoints a person uses to act on what
+// the service held back or flagged. it has its own key, so releasing held transactions
+// doesn't take the key that writes to null-core
+package admin
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// RequireKey serves h only to requests with an "Authorization: Bearer <key>" header.
+// every request is refused when key is empty
+func RequireKey(key string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if key == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(key)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"os"
@@ -100,19 +101,33 @@ func (c *Client) GetAccounts(userID string) ([]*pb.Account, error) {
 	return resp.Accounts, nil
 }
 
-func (c *Client) CreateAccount(userID, name, bank string) (*pb.Account, error) {
+// NewAccount describes an account to create for transactions of an unknown account
+type NewAccount struct {
+	Name     string // account number or name as the bank writes it, e.g. "1001"
+	Bank     string
+	Kind     domain.AccountKind // chequing when unknown
+	Currency string             // CAD when empty
+	Alias    string             // readable name, e.g. "RBC Credit Card 1001"
+}
+
+func (c *Client) CreateAccount(userID string, acc NewAccount) (*pb.Account, error) {
 	ctx := c.withAuth(context.Background())
 
+	currency := cmp.Or(acc.Currency, "CAD")
 	req := &pb.CreateAccountRequest{
 		UserId: userID,
-		Name:   name,
-		Bank:   bank,
-		Type:   pb.AccountType_ACCOUNT_CHEQUING,
+		Name:   acc.Name,
+		Bank:   acc.Bank,
+		Type:   convertAccountKind(acc.Kind),
 		AnchorBalance: &money.Money{
-			CurrencyCode: "CAD",
+			CurrencyCode: currency,
 			Units:        0,
 			Nanos:        0,
 		},
+		MainCurrency: currency,
+	}
+	if acc.Alias != "" {
+		req.Alias = &acc.Alias
 	}
 
 	resp, err := c.accountClient.CreateAccount(ctx, req)
@@ -120,66 +135,111 @@ func (c *Client) CreateAccount(userID, name, bank string) (*pb.Account, error) {
 		return nil, fmt.Errorf("failed to create account: %w", err)
 	}
 
-	c.log.Info("successfully created account", "name", name, "bank", bank, "account_id", resp.Account.Id)
+	c.log.Info("successfully created account", "name", acc.Name, "bank", acc.Bank, "type", req.Type, "currency", currency, "account_id", resp.Account.Id)
 	return resp.Account, nil
 }
 
 func (c *Client) CreateTransaction(userID string, tx *domain.Transaction) error {
-	ctx := c.withAuth(context.Background())
+	return c.CreateTransactions(userID, []*domain.Transaction{tx})
+}
 
-	// convert domain transaction to TransactionInput
-	txInput := &pb.TransactionInput{
-		AccountId: int64(tx.AccountID),
-		TxDate:    timestamppb.New(tx.TxDate),
-		TxAmount: &money.Money{
-			CurrencyCode: tx.TxCurrency,
-			Units:        int64(tx.TxAmount),
-			Nanos:        int32((tx.TxAmount - float64(int64(tx.TxAmount))) * 1e9),
-		},
-		Direction: c.convertDirection(tx.TxDirection),
-	}
+// CreateTransactions creates all transactions parsed from one email in a single request
+func (c *Client) CreateTransactions(userID string, txs []*domain.Transaction) error {
+	ctx := c.withAuth(context.Background())
 
-	// Optional fields
-	if tx.TxDesc != "" {
-		txInput.Description = &tx.TxDesc
-	}
-	if tx.Merchant != "" {
-		txInput.Merchant = &tx.Merchant
-	}
-	if tx.UserNotes != "" {
-		txInput.UserNotes = &tx.UserNotes
+	inputs := make([]*pb.TransactionInput, 0, len(txs))
+	for _, tx := range txs {
+		inputs = append(inputs, c.toTransactionInput(tx))
 	}
 
-	// create bulk request with single transaction
 	req := &pb.CreateTransactionRequest{
 		UserId:       userID,
-		Transactions: []*pb.TransactionInput{txInput},
+		Transactions: inputs,
+	}
+
+	emailID := ""
+	if len(txs) > 0 {
+		emailID = txs[0].EmailID
 	}
 
 	resp, err := c.txClient.CreateTransaction(ctx, req)
 	if err != nil {
 		// check for duplicate transaction (conflict)
 		if grpcStatus := status.Code(err); grpcStatus == codes.AlreadyExists {
-			c.log.Info("skipping duplicate transaction", "email_id", tx.EmailID)
+			c.log.Info("skipping duplicate transaction", "email_id", emailID)
 			return nil // not a fatal error, just a duplicate
 		}
 		return fmt.Errorf("failed to create transaction: %w", err)
 	}
 
 	if len(resp.Transactions) > 0 {
-		c.log.Info("transaction created successfully", "email_id", tx.EmailID, "tx_id", resp.Transactions[0].Id)
+		for _, created := range resp.Transactions {
+			c.log.Info("transaction created successfully", "email_id", emailID, "tx_id", created.Id)
+		}
 	} else {
-		c.log.Info("transaction request completed", "email_id", tx.EmailID, "created_count", resp.CreatedCount)
+		c.log.Info("transaction request completed", "email_id", emailID, "created_count", resp.CreatedCount)
 	}
 	return nil
 }
 
+// toTransactionInput converts a domain transaction to TransactionInput
+func (c *Client) toTransactionInput(tx *domain.Transaction) *pb.TransactionInput {
+	txInput := &pb.TransactionInput{
+		AccountId: int64(tx.AccountID),
+		TxDate:    timestamppb.New(tx.TxDate),
+		TxAmount:  toMoney(tx.TxAmount, tx.TxCurrency),
+		Direction: c.convertDirection(tx.TxDirection),
+	}
+
+	// Optional fields
+	if tx.TxDesc != "" {
+		txInput.Description = &tx.TxDesc
+	}
+	if tx.Merchant != "" {
+		txInput.Merchant = &tx.Merchant
+	}
+	if tx.UserNotes != "" {
+		txInput.UserNotes = &tx.UserNotes
+	}
+	if tx.ForeignAmount != nil && tx.ForeignCurrency != nil {
+		txInput.ForeignAmount = toMoney(*tx.ForeignAmount, *tx.ForeignCurrency)
+		if tx.ExchangeRate != nil {
+			rate := tx.ExchangeRate.Float64()
+			txInput.ExchangeRate = &rate
+		}
+	}
+
+	return txInput
+}
+
+// toMoney converts an amount to google.type.Money, exactly
+func toMoney(d domain.Decimal, currency string) *money.Money {
+	return &money.Money{CurrencyCode: currency, Units: d.Units(), Nanos: d.Nanos()}
+}
+
 // withAuth adds authentication metadata to the context
 func (c *Client) withAuth(ctx context.Context) context.Context {
 	md := metadata.Pairs("x-internal-key", c.authToken)
 	return metadata.NewOutgoingContext(ctx, md)
 }
 
+// convertAccountKind converts domain AccountKind to gRPC AccountType. null-core has no
+// line of credit type, and accounts of unknown kind stay chequing as they always were
+func convertAccountKind(kind domain.AccountKind) pb.AccountType {
+	switch kind {
+	case domain.CreditCard:
+		return pb.AccountType_ACCOUNT_CREDIT_CARD
+	case domain.Savings:
+		return pb.AccountType_ACCOUNT_SAVINGS
+	case domain.Investment:
+		return pb.AccountType_ACCOUNT_INVESTMENT
+	case domain.LineOfCredit:
+		return pb.AccountType_ACCOUNT_OTHER
+	default:
+		return pb.AccountType_ACCOUNT_CHEQUING
+	}
+}
+
 // convertDirection converts domain Direction to gRPC TransactionDirection
 func (c *Client) convertDirection(dir domain.Direction) pb.TransactionDirection {
 	switch dir {
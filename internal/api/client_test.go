@@ -0,0 +1,75 @@
+package api
+
+import (
+	"testing"
+
+	"null-email-parser/internal/domain"
+	pb "null-email-parser/internal/gen/null/v1"
+)
+
+// amounts reach null-core exactly, $39.50 is 39 units and 500000000 nanos
+func TestToMoney(t *testing.T) {
+	tests := []struct {
+		amount string
+		units  int64
+		nanos  int32
+	}{
+		{"39.50", 39, 500_000_000},
+		{"0.1", 0, 100_000_000},
+		{"0.30", 0, 300_000_000},
+		{"1234567.89", 1234567, 890_000_000},
+		{"19.99", 19, 990_000_000},
+		{"-5.01", -5, -10_000_000},
+		{"0.000000001", 0, 1},
+	}
+	for _, tt := range tests {
+		m := toMoney(domain.MustDecimal(tt.amount), "CAD")
+		if m.Units != tt.units || m.Nanos != tt.nanos || m.CurrencyCode != "CAD" {
+			t.Errorf("toMoney(%s) = %d, %d, %s; want %d, %d", tt.amount, m.Units, m.Nanos, m.CurrencyCode, tt.units, tt.nanos)
+		}
+		back, err := domain.NewDecimal(m.Units, m.Nanos)
+		if err != nil || back != domain.MustDecimal(tt.amount) {
+			t.Errorf("round trip of %s = %s, %v", tt.amount, back, err)
+		}
+	}
+}
+
+func TestToTransactionInputForeign(t *testing.T) {
+	foreign, rate, currency := domain.MustDecimal("12.00"), domain.MustDecimal("1.3767"), "USD"
+	tx := &domain.Transaction{
+		TxAmount:        domain.MustDecimal("16.52"),
+		TxCurrency:      "CAD",
+		ForeignAmount:   &foreign,
+		ForeignCurrency: &currency,
+		ExchangeRate:    &rate,
+	}
+
+	in := (&Client{}).toTransactionInput(tx)
+	if m := in.GetForeignAmount(); m.GetUnits() != 12 || m.GetNanos() != 0 || m.GetCurrencyCode() != "USD" {
+		t.Errorf("foreign amount = %v", m)
+	}
+	if in.ExchangeRate == nil || *in.ExchangeRate != 1.3767 {
+		t.Errorf("exchange rate = %v", in.ExchangeRate)
+	}
+
+	in = (&Client{}).toTransactionInput(&domain.Transaction{TxAmount: domain.MustDecimal("1"), TxCurrency: "CAD"})
+	if in.ForeignAmount != nil || in.ExchangeRate != nil {
+		t.Errorf("foreign fields set on a domestic transaction: %v", in)
+	}
+}
+
+func TestConvertAccountKind(t *testing.T) {
+	tests := map[domain.AccountKind]pb.AccountType{
+		domain.CreditCard:     pb.AccountType_ACCOUNT_CREDIT_CARD,
+		domain.Savings:        pb.AccountType_ACCOUNT_SAVINGS,
+		domain.Chequing:       pb.AccountType_ACCOUNT_CHEQUING,
+		domain.Investment:     pb.AccountType_ACCOUNT_INVESTMENT,
+		domain.LineOfCredit:   pb.AccountType_ACCOUNT_OTHER,
+		domain.UnknownAccount: pb.AccountType_ACCOUNT_CHEQUING,
+	}
+	for kind, want := range tests {
+		if got := convertAccountKind(kind); got != want {
+			t.Errorf("convertAccountKind(%q) = %v; want %v", kind, got, want)
+		}
+	}
+}
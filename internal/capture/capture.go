@@ -0,0 +1,162 @@
+// Package capture records emails the parsers could not handle as anonymized fixtures,
+// so they can be turned into test cases without copying raw emails off the server
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"null-email-parser/internal/fixture"
+)
+
+// Outcome is why an email was captured
+type Outcome string
+
+const (
+	Unmatched Outcome = "unmatched" // no parser matched
+	Failed    Outcome = "failed"    // the matching parser returned an error
+	Ambiguous Outcome = "ambiguous" // several parsers of the same priority matched
+)
+
+// Outcomes are all outcomes, in the order they are documented
+var Outcomes = []Outcome{Unmatched, Failed, Ambiguous}
+
+// MetaSuffix of the file describing a capture, next to its fixture
+const MetaSuffix = ".capture.json"
+
+// DefaultLimit is the number of captures kept before new ones are dropped
+const DefaultLimit = 100
+
+// Meta describes a captured email
+type Meta struct {
+	Outcome  Outcome   `json:"outcome"`
+	Parser   string    `json:"parser,omitempty"` // id of the matching parser
+	Bank     string    `json:"bank,omitempty"`   // bank of the matching parser
+	Error    string    `json:"error,omitempty"`
+	Subject  string    `json:"subject"` // anonymized, set by Record
+	Captured time.Time `json:"captured"`
+}
+
+// Recorder writes captures to a directory
+type Recorder struct {
+	Dir      string
+	Outcomes []Outcome // outcomes to record
+	Limit    int       // captures kept in Dir, 0 for no limit
+
+	mu sync.Mutex
+}
+
+// ParseOutcomes parses a comma separated list of outcomes, "all" for every outcome
+func ParseOutcomes(s string) ([]Outcome, error) {
+	var out []Outcome
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		switch {
+		case name == "":
+		case name == "all":
+			out = append(out, Outcomes...)
+		case slices.Contains(Outcomes, Outcome(name)):
+			out = append(out, Outcome(name))
+		default:
+			return nil, fmt.Errorf("unknown capture outcome %q", name)
+		}
+	}
+	return out, nil
+}
+
+// Wants reports whether emails with outcome are recorded
+func (r *Recorder) Wants(outcome Outcome) bool {
+	return r != nil && slices.Contains(r.Outcomes, outcome)
+}
+
+// Record anonymizes a raw email and writes it with its meta. it returns the path of
+// the fixture, or "" when the outcome is not recorded or the limit is reached
+func (r *Recorder) Record(raw []byte, meta Meta) (string, error) {
+	if !r.Wants(meta.Outcome) {
+		return "", nil
+	}
+
+	f, err := fixture.Decode(raw)
+	if err != nil {
+		return "", err
+	}
+	if meta.Captured.IsZero() {
+		meta.Captured = time.Now().UTC()
+	}
+	meta.Subject = f.Subject // anonymized, the subject as received may name someone
+	metaData, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(r.Dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	if r.Limit > 0 {
+		captures, err := List(r.Dir)
+		if err != nil {
+			return "", err
+		}
+		if len(captures) >= r.Limit {
+			return "", nil
+		}
+	}
+
+	f.Name = string(meta.Outcome) + "-" + f.Name
+	path, err := f.Write(r.Dir)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(MetaPath(path), append(metaData, '\n'), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// MetaPath returns the path of the meta of a captured fixture
+func MetaPath(fixturePath string) string {
+	return strings.TrimSuffix(fixturePath, fixture.Suffix) + MetaSuffix
+}
+
+// Capture is a recorded email
+type Capture struct {
+	Path string // fixture path
+	Meta Meta
+}
+
+// List returns the captures in dir, oldest first. fixtures without meta are listed as
+// unmatched
+func List(dir string) ([]Capture, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+fixture.Suffix))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Capture, 0, len(paths))
+	for _, path := range paths {
+		c := Capture{Path: path, Meta: Meta{Outcome: Unmatched}}
+		data, err := os.ReadFile(MetaPath(path))
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return nil, err
+		default:
+			if err := json.Unmarshal(data, &c.Meta); err != nil {
+				return nil, fmt.Errorf("%s: %w", MetaPath(path), err)
+			}
+		}
+		out = append(out, c)
+	}
+
+	slices.SortStableFunc(out, func(a, b Capture) int { return a.Meta.Captured.Compare(b.Meta.Captured) })
+	return out, nil
+}
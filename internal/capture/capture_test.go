@@ -0,0 +1,106 @@
+package capture
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const raw = "Subject: You made a purchase.\r\n" +
+	"From: RBC Royal Bank <alerts@rbc.example>\r\n" +
+	"To: Jane Doe <jane@personal.example>\r\n" +
+	"Date: Sat, 13 Sep 2025 10:00:00 -0600\r\n" +
+	"Content-Type: text/plain\r\n\r\n" +
+	"A purchase of $39.50 was made on card ************4821 by JANE DOE\r\n"
+
+func TestRecord(t *testing.T) {
+	r := &Recorder{Dir: t.TempDir(), Outcomes: []Outcome{Failed}, Limit: 2}
+
+	path, err := r.Record([]byte(raw), Meta{Outcome: Unmatched})
+	if err != nil || path != "" {
+		t.Fatalf("Record(unmatched) = %q, %v; want it skipped", path, err)
+	}
+
+	captured := time.Date(2025, 9, 13, 16, 0, 0, 0, time.UTC)
+	path, err = r.Record([]byte(raw), Meta{Outcome: Failed, Parser: "rbc.purchase", Bank: "rbc", Error: "amount not found", Captured: captured})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(path, "failed-you-made-a-purchase.decoded.eml") {
+		t.Errorf("path = %q", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leak := range []string{"jane@personal.example", "4821", "JANE DOE"} {
+		if strings.Contains(string(data), leak) {
+			t.Errorf("capture contains %q:\n%s", leak, data)
+		}
+	}
+
+	if _, err := r.Record([]byte(raw), Meta{Outcome: Failed}); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := r.Record([]byte(raw), Meta{Outcome: Failed}); err != nil || path != "" {
+		t.Errorf("Record past the limit = %q, %v; want it dropped", path, err)
+	}
+
+	captures, err := List(r.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(captures) != 2 {
+		t.Fatalf("List returned %d captures; want 2", len(captures))
+	}
+	got := captures[0].Meta
+	if got.Parser != "rbc.purchase" || got.Bank != "rbc" || got.Error != "amount not found" ||
+		got.Subject != "You made a purchase." || !got.Captured.Equal(captured) {
+		t.Errorf("meta = %+v", got)
+	}
+}
+
+// the subject names the payer of e-transfers, the file name and meta must not
+func TestRecordSubject(t *testing.T) {
+	r := &Recorder{Dir: t.TempDir(), Outcomes: []Outcome{Unmatched}}
+	email := strings.Replace(raw, "Subject: You made a purchase.", "Subject: INTERAC e-Transfer: JOHN DOE sent you money", 1)
+
+	path, err := r.Record([]byte(email), Meta{Outcome: Unmatched, Subject: "INTERAC e-Transfer: JOHN DOE sent you money"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := os.ReadFile(MetaPath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []string{path, string(meta)} {
+		if strings.Contains(strings.ToLower(got), "john") {
+			t.Errorf("capture names the payer: %s", got)
+		}
+	}
+	if !strings.Contains(string(meta), "INTERAC e-Transfer:") {
+		t.Errorf("meta lost the rest of the subject: %s", meta)
+	}
+}
+
+func TestRecordNil(t *testing.T) {
+	var r *Recorder
+	if r.Wants(Failed) {
+		t.Error("nil recorder wants captures")
+	}
+}
+
+func TestParseOutcomes(t *testing.T) {
+	got, err := ParseOutcomes(" Failed, unmatched ")
+	if err != nil || len(got) != 2 || got[0] != Failed || got[1] != Unmatched {
+		t.Errorf("ParseOutcomes = %v, %v", got, err)
+	}
+	if got, _ := ParseOutcomes("all"); len(got) != len(Outcomes) {
+		t.Errorf("ParseOutcomes(all) = %v", got)
+	}
+	if _, err := ParseOutcomes("unmatched,nope"); err == nil {
+		t.Error("unknown outcome accepted")
+	}
+}
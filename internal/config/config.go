@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 )
@@ -21,6 +23,26 @@ type Config struct {
 
 	UnsafeSaveEML bool // save incoming emails to disk for debugging
 
+	ParsersDir string // directory of declarative parser definitions and scripts, reloaded on SIGHUP
+
+	CaptureDir      string // directory unhandled emails are recorded to as anonymized fixtures
+	CaptureOutcomes string // comma separated outcomes to record, see capture.Outcomes
+	CaptureLimit    int    // captures kept in CaptureDir
+
+	DriftState     string // file the drift fingerprints are kept in across restarts
+	MetricsAddress string // http address serving expvar metrics at /debug/vars, "" to disable
+
+	HeuristicSenders   []string // sender domains the heuristic extractor handles, none to disable it
+	HeuristicCurrency  string   // currency of their amounts without one, e.g. "$12.00"
+	HeuristicReviewDir string   // where guessed transactions are held for review, "" to create them
+
+	PendingState   string // file transactions of unknown accounts are held in until confirmed, "" to create the accounts
+	PendingWebhook string // url told about every newly held account, "" to only log it
+	AdminAddress   string // http address of the admin api for held transactions and drift alerts, "" to disable
+	AdminKey       string // bearer token the admin api requires, separate from APIKey
+
+	CoverageInterval time.Duration // how often the captures are rerun through the registry and reported, 0 to disable
+
 	LogLevel log.Level // logging level
 }
 
@@ -73,16 +95,82 @@ func Load() Config {
 	// Can be disabled with UNSAFE_DISABLE_TLS_REQUIRED=true
 	tlsRequired := tlsCert != "" && tlsKey != "" && os.Getenv("UNSAFE_DISABLE_TLS_REQUIRED") == ""
 
+	captureOutcomes := os.Getenv("CAPTURE")
+	if captureOutcomes == "" {
+		captureOutcomes = "unmatched,failed"
+	}
+
+	captureLimit, err := strconv.Atoi(os.Getenv("CAPTURE_LIMIT"))
+	if err != nil {
+		captureLimit = 100
+	}
+
+	var heuristicSenders []string
+	for _, s := range strings.Split(os.Getenv("HEURISTIC_SENDERS"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			heuristicSenders = append(heuristicSenders, s)
+		}
+	}
+
+	heuristicCurrency := strings.ToUpper(strings.TrimSpace(os.Getenv("HEURISTIC_CURRENCY")))
+	if heuristicCurrency == "" {
+		heuristicCurrency = "CAD"
+	}
+
+	heuristicReviewDir := "review"
+	if os.Getenv("HEURISTIC_MODE") == "create" {
+		heuristicReviewDir = ""
+	} else if dir := os.Getenv("HEURISTIC_REVIEW_DIR"); dir != "" {
+		heuristicReviewDir = dir
+	}
+
+	metricsAddress := os.Getenv("METRICS_PORT")
+	if metricsAddress != "" {
+		metricsAddress = parseAddress(metricsAddress)
+	}
+
+	pendingState := ""
+	if os.Getenv("ACCOUNT_MODE") == "confirm" {
+		pendingState = os.Getenv("PENDING_STATE")
+		if pendingState == "" {
+			pendingState = "pending.json"
+		}
+	}
+
+	adminAddress := os.Getenv("ADMIN_PORT")
+	if adminAddress != "" {
+		adminAddress = parseAddress(adminAddress)
+	}
+
+	coverageInterval, err := time.ParseDuration(os.Getenv("COVERAGE_INTERVAL"))
+	if err != nil {
+		coverageInterval = 0
+	}
+
 	return Config{
-		NullCoreURL:   nullCoreURL,
-		APIKey:        apiKey,
-		Domain:        domain,
-		SMTPAddress:   parseAddress(smtpAddress),
-		GRPCAddress:   parseAddress(grpcAddress),
-		TLSCert:       tlsCert,
-		TLSKey:        tlsKey,
-		TLSRequired:   tlsRequired,
-		UnsafeSaveEML: os.Getenv("UNSAFE_SAVE_EML") != "",
-		LogLevel:      logLevel,
+		NullCoreURL:        nullCoreURL,
+		APIKey:             apiKey,
+		Domain:             domain,
+		SMTPAddress:        parseAddress(smtpAddress),
+		GRPCAddress:        parseAddress(grpcAddress),
+		TLSCert:            tlsCert,
+		TLSKey:             tlsKey,
+		TLSRequired:        tlsRequired,
+		UnsafeSaveEML:      os.Getenv("UNSAFE_SAVE_EML") != "",
+		ParsersDir:         os.Getenv("PARSERS_DIR"),
+		CaptureDir:         os.Getenv("CAPTURE_DIR"),
+		CaptureOutcomes:    captureOutcomes,
+		CaptureLimit:       captureLimit,
+		CoverageInterval:   coverageInterval,
+		DriftState:         os.Getenv("DRIFT_STATE"),
+		HeuristicSenders:   heuristicSenders,
+		HeuristicCurrency:  heuristicCurrency,
+		HeuristicReviewDir: heuristicReviewDir,
+		MetricsAddress:     metricsAddress,
+		PendingState:       pendingState,
+		PendingWebhook:     os.Getenv("PENDING_WEBHOOK"),
+		AdminAddress:       adminAddress,
+		AdminKey:           os.Getenv("ADMIN_KEY"),
+		LogLevel:           logLevel,
 	}
 }
@@ -0,0 +1,235 @@
+// Package coverage runs a corpus of emails through the parser registry and reports
+// what it handles, grouping the unmatched emails by sender and template so it is
+// clear which parsers to write next
+package coverage
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"null-email-parser/internal/parser"
+)
+
+// Outcome of running one email through the registry
+type Outcome string
+
+const (
+	Parsed    Outcome = "parsed"
+	Failed    Outcome = "failed"
+	Unmatched Outcome = "unmatched"
+	Ambiguous Outcome = "ambiguous" // also counted as parsed or failed
+)
+
+// Counts of emails by outcome
+type Counts struct {
+	Total     int `json:"total"`
+	Parsed    int `json:"parsed"`
+	Failed    int `json:"failed"`
+	Unmatched int `json:"unmatched"`
+	Ambiguous int `json:"ambiguous"`
+}
+
+// Coverage is the share of emails parsed
+func (c Counts) Coverage() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Parsed) / float64(c.Total)
+}
+
+func (c *Counts) add(o Outcome, ambiguous bool) {
+	c.Total++
+	switch o {
+	case Parsed:
+		c.Parsed++
+	case Failed:
+		c.Failed++
+	case Unmatched:
+		c.Unmatched++
+	}
+	if ambiguous {
+		c.Ambiguous++
+	}
+}
+
+// Cluster is a group of unmatched emails from one sender domain sharing a subject
+// template
+type Cluster struct {
+	Domain   string   `json:"domain"`
+	Subject  string   `json:"subject"` // subject template, numbers replaced with #
+	Count    int      `json:"count"`
+	Variants int      `json:"variants"` // distinct body templates
+	Examples []string `json:"examples"` // a few email names
+}
+
+// ParserCounts are the counts of the emails one parser matched
+type ParserCounts struct {
+	ID   string `json:"id"`
+	Bank string `json:"bank"`
+	Counts
+}
+
+// Report is the result of a coverage run
+type Report struct {
+	Counts
+	Banks    map[string]*Counts `json:"banks"` // by bank, unmatched emails by sender domain
+	Parsers  []*ParserCounts    `json:"parsers"`
+	Clusters []*Cluster         `json:"clusters"` // largest first
+	Errors   []string           `json:"errors,omitempty"`
+
+	variants map[*Cluster]map[string]bool
+}
+
+// maxExamples per cluster
+const maxExamples = 3
+
+// NewReport returns an empty report
+func NewReport() *Report {
+	return &Report{Banks: map[string]*Counts{}, variants: map[*Cluster]map[string]bool{}}
+}
+
+// Add runs an email through the registry and counts the outcome
+func (r *Report) Add(meta parser.EmailMeta) Outcome {
+	p, err := parser.Lookup(meta)
+	ambiguous := err != nil
+
+	if p == nil {
+		r.unmatched(meta)
+		return Unmatched
+	}
+
+	outcome := Parsed
+	if _, err := p.ParseAll(meta); err != nil {
+		outcome = Failed
+	}
+
+	info := parser.Describe(p)
+	r.Counts.add(outcome, ambiguous)
+	r.bank(info.Bank).add(outcome, ambiguous)
+
+	i := slices.IndexFunc(r.Parsers, func(pc *ParserCounts) bool { return pc.ID == info.ID })
+	if i < 0 {
+		r.Parsers = append(r.Parsers, &ParserCounts{ID: info.ID, Bank: info.Bank})
+		i = len(r.Parsers) - 1
+	}
+	r.Parsers[i].add(outcome, ambiguous)
+
+	if ambiguous {
+		return Ambiguous
+	}
+	return outcome
+}
+
+func (r *Report) unmatched(meta parser.EmailMeta) {
+	domain := meta.SenderDomain()
+	if domain == "" {
+		domain = "unknown"
+	}
+	r.Counts.add(Unmatched, false)
+	r.bank(bankOf(domain)).add(Unmatched, false)
+
+	subject := Template(meta.Subject)
+	i := slices.IndexFunc(r.Clusters, func(c *Cluster) bool { return c.Domain == domain && c.Subject == subject })
+	if i < 0 {
+		r.Clusters = append(r.Clusters, &Cluster{Domain: domain, Subject: subject})
+		i = len(r.Clusters) - 1
+	}
+
+	c := r.Clusters[i]
+	c.Count++
+	if len(c.Examples) < maxExamples {
+		c.Examples = append(c.Examples, meta.ID)
+	}
+	if r.variants[c] == nil {
+		r.variants[c] = map[string]bool{}
+	}
+	r.variants[c][Fingerprint(meta.Text)] = true
+	c.Variants = len(r.variants[c])
+}
+
+func (r *Report) bank(name string) *Counts {
+	if r.Banks[name] == nil {
+		r.Banks[name] = &Counts{}
+	}
+	return r.Banks[name]
+}
+
+// bankOf returns the bank whose parsers handle domain, or the domain itself
+func bankOf(domain string) string {
+	for _, info := range parser.Registered() {
+		for _, d := range info.Domains {
+			if domain == d || strings.HasSuffix(domain, "."+d) {
+				return info.Bank
+			}
+		}
+	}
+	return domain
+}
+
+// Sort orders clusters largest first and parsers by id
+func (r *Report) Sort() {
+	slices.SortStableFunc(r.Clusters, func(a, b *Cluster) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Domain, b.Domain), strings.Compare(a.Subject, b.Subject))
+	})
+	slices.SortFunc(r.Parsers, func(a, b *ParserCounts) int { return strings.Compare(a.ID, b.ID) })
+}
+
+var (
+	templateEmail  = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+	templateURL    = regexp.MustCompile(`https?://\S+`)
+	templateNumber = regexp.MustCompile(`[$€£]?\d[\d,.*/:-]*`)
+	templateMasked = regexp.MustCompile(`\*{2,}#?`)
+	templateSpace  = regexp.MustCompile(`\s+`)
+)
+
+// Template reduces text to its template: addresses, links, numbers, amounts and
+// dates are replaced with placeholders, whitespace collapsed and case folded
+func Template(s string) string {
+	s = templateURL.ReplaceAllString(s, "<url>")
+	s = templateEmail.ReplaceAllString(s, "<email>")
+	s = templateNumber.ReplaceAllString(s, "#")
+	s = templateMasked.ReplaceAllString(s, "#")
+	s = templateSpace.ReplaceAllString(s, " ")
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// Fingerprint is a short hash of the template of a body
+func Fingerprint(text string) string {
+	sum := sha256.Sum256([]byte(Template(text)))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Scan adds every .eml file under the paths to a new report. unreadable emails are
+// listed in Errors
+func Scan(paths ...string) (*Report, error) {
+	r := NewReport()
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".eml") {
+				return nil
+			}
+
+			meta, err := parser.ReadEmailMeta(path)
+			if err != nil {
+				r.Errors = append(r.Errors, err.Error())
+				return nil
+			}
+			r.Add(meta)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	r.Sort()
+	return r, nil
+}
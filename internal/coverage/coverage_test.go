@@ -0,0 +1,85 @@
+package coverage
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"null-email-parser/internal/domain"
+	"null-email-parser/internal/parser"
+)
+
+// testParser matches subjects containing "purchase" and fails on "broken" bodies
+type testParser struct{}
+
+func (testParser) Match(m parser.EmailMeta) bool { return strings.Contains(m.Subject, "purchase") }
+
+func (testParser) Parse(m parser.EmailMeta) (*domain.Transaction, error) {
+	if strings.Contains(m.Text, "broken") {
+		return nil, errors.New("amount not found")
+	}
+	return &domain.Transaction{}, nil
+}
+
+func (testParser) Describe() parser.Info {
+	return parser.Info{ID: "testbank.purchase", Domains: []string{"testbank.example"}}
+}
+
+func meta(id, from, subject, text string) parser.EmailMeta {
+	return parser.EmailMeta{ID: id, Subject: subject, Text: text, FromAddress: &mail.Address{Address: from}}
+}
+
+func TestReport(t *testing.T) {
+	parser.RegisterSet("coverage-test", []parser.MultiParser{parser.AsMulti(testParser{})})
+	t.Cleanup(func() { parser.RegisterSet("coverage-test", nil) })
+
+	r := NewReport()
+	outcomes := []Outcome{
+		r.Add(meta("a", "alerts@testbank.example", "You made a purchase", "$12.00 at SHOP")),
+		r.Add(meta("b", "alerts@testbank.example", "You made a purchase", "broken")),
+		r.Add(meta("c", "alerts@testbank.example", "Statement 12 ready", "balance $1.00")),
+		r.Add(meta("d", "alerts@testbank.example", "Statement 7 ready", "balance $99.10")),
+		r.Add(meta("e", "news@other.example", "Statement 7 ready", "hello")),
+	}
+	r.Sort()
+
+	want := []Outcome{Parsed, Failed, Unmatched, Unmatched, Unmatched}
+	for i := range want {
+		if outcomes[i] != want[i] {
+			t.Errorf("outcome %d = %s; want %s", i, outcomes[i], want[i])
+		}
+	}
+
+	if r.Total != 5 || r.Parsed != 1 || r.Failed != 1 || r.Unmatched != 3 {
+		t.Errorf("counts = %+v", r.Counts)
+	}
+	if bank := r.Banks["testbank"]; bank == nil || bank.Total != 4 || bank.Unmatched != 2 {
+		t.Errorf("testbank counts = %+v; want the unmatched statements counted by sender domain", bank)
+	}
+	if len(r.Parsers) != 1 || r.Parsers[0].ID != "testbank.purchase" || r.Parsers[0].Failed != 1 {
+		t.Errorf("parsers = %+v", r.Parsers)
+	}
+
+	if len(r.Clusters) != 2 {
+		t.Fatalf("clusters = %+v; want statements grouped per domain", r.Clusters)
+	}
+	c := r.Clusters[0]
+	if c.Domain != "testbank.example" || c.Subject != "statement # ready" || c.Count != 2 || c.Variants != 1 {
+		t.Errorf("largest cluster = %+v", c)
+	}
+	if strings.Join(c.Examples, ",") != "c,d" {
+		t.Errorf("examples = %v", c.Examples)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	a := Template("Purchase of $1,234.56 on card ************4821 on 2025-09-13, see https://bank.example/x?id=1")
+	b := Template("purchase of $7.00 on card ****1001 on 2024-01-02,   see https://bank.example/y")
+	if a != b {
+		t.Errorf("templates differ:\n%s\n%s", a, b)
+	}
+	if Fingerprint("Dear client") == Fingerprint("Dear customer") {
+		t.Error("different templates have the same fingerprint")
+	}
+}
@@ -0,0 +1,47 @@
+package coverage
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteText writes the report as tables
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "%d emails, %.0f%% parsed, %d failed, %d unmatched, %d ambiguous\n\n",
+		r.Total, 100*r.Coverage(), r.Failed, r.Unmatched, r.Ambiguous)
+
+	fmt.Fprintln(tw, "BANK\tEMAILS\tPARSED\tFAILED\tUNMATCHED\tCOVERAGE")
+	for _, name := range slices.Sorted(maps.Keys(r.Banks)) {
+		c := r.Banks[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.0f%%\n", name, c.Total, c.Parsed, c.Failed, c.Unmatched, 100*c.Coverage())
+	}
+
+	if len(r.Parsers) > 0 {
+		fmt.Fprintln(tw, "\nPARSER\tEMAILS\tPARSED\tFAILED\tAMBIGUOUS")
+		for _, p := range r.Parsers {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", p.ID, p.Total, p.Parsed, p.Failed, p.Ambiguous)
+		}
+	}
+
+	if len(r.Clusters) > 0 {
+		fmt.Fprintln(tw, "\nUNMATCHED DOMAIN\tEMAILS\tVARIANTS\tSUBJECT\tEXAMPLES")
+		for _, c := range r.Clusters {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", c.Domain, c.Count, c.Variants, c.Subject, strings.Join(c.Examples, " "))
+		}
+	}
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintln(tw, "\nUNREADABLE")
+		for _, e := range r.Errors {
+			fmt.Fprintln(tw, e)
+		}
+	}
+
+	return tw.Flush()
+}
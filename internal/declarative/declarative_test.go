@@ -0,0 +1,206 @@
+package declarative
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"null-email-parser/internal/domain"
+	"null-email-parser/internal/parser"
+	"null-email-parser/internal/parser/parsertest"
+)
+
+func TestLoadDir(t *testing.T) {
+	parsers, err := LoadDir(filepath.Join("testdata", "valid"))
+	if err != nil {
+		t.Fatalf("LoadDir returned error: %v", err)
+	}
+	if len(parsers) != 2 {
+		t.Fatalf("loaded %d parsers; want 2", len(parsers))
+	}
+
+	byID := map[string]*Parser{}
+	for _, p := range parsers {
+		byID[p.Name()] = p
+	}
+
+	purchase := byID["rbc-purchase-json"]
+	meta := parsertest.LoadMeta(t, "../email/rbc/testdata/fw-you-made-a-purchase.decoded.eml")
+	if !purchase.Match(meta) {
+		t.Fatal("json parser did not match the purchase email")
+	}
+	txn, err := purchase.Parse(meta)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if txn.TxAccount != "************1001" || txn.TxAmount != domain.MustDecimal("39.50") || txn.TxCurrency != "CAD" ||
+		txn.TxDirection != domain.Out || txn.TxDesc != "SOME NO FRILLS 0000" {
+		t.Errorf("transaction = %+v", txn)
+	}
+
+	deposit := byID["rbc-deposit-yaml"]
+	meta = parsertest.LoadMeta(t, "../email/rbc/testdata/deposit-notice.decoded.eml")
+	if !deposit.Match(meta) || purchase.Match(meta) {
+		t.Fatal("deposit email should only match the deposit parser")
+	}
+	txn, err = deposit.Parse(meta)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if txn.TxAccount != "Savings" || txn.TxAmount != domain.MustDecimal("2.65") || txn.TxDesc != "RBC Deposit" || txn.TxDirection != domain.In {
+		t.Errorf("transaction = %+v", txn)
+	}
+}
+
+func TestLoadDirValidation(t *testing.T) {
+	parsers, err := LoadDir(filepath.Join("testdata", "invalid"))
+	if len(parsers) != 0 {
+		t.Errorf("loaded %d parsers from invalid definitions", len(parsers))
+	}
+	if err == nil {
+		t.Fatal("LoadDir returned no error for invalid definitions")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		`broken.yaml: direction: must be "in" or "out", got "sideways"`,
+		`broken.yaml: match.subject: invalid regex`,
+		`broken.yaml: fields.amount.regex: needs a capture group`,
+		`broken.yaml: fields.txdate.optional: cannot be set, the field is required`,
+		`broken.yaml: fields.txdate: needs a regex or a label`,
+		`broken.yaml: description: invalid template`,
+		`typo.yml: invalid syntax`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error does not mention %q:\n%s", want, msg)
+		}
+	}
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Error("errors should be ValidationErrors")
+	}
+}
+
+func TestParseErrorNamesSpec(t *testing.T) {
+	parsers, err := LoadDir(filepath.Join("testdata", "valid"))
+	if err != nil {
+		t.Fatalf("LoadDir returned error: %v", err)
+	}
+
+	for _, p := range parsers {
+		if p.Name() != "rbc-purchase-json" {
+			continue
+		}
+		_, err := p.Parse(parser.EmailMeta{Subject: "You made a purchase", Text: "RBC Royal Bank"})
+
+		var perr *parser.ParseError
+		if !errors.As(err, &perr) || perr.Parser != "rbc-purchase-json" || perr.Field != "amount" {
+			t.Errorf("err = %v; want a ParseError for amount naming the spec", err)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	spec, err := os.ReadFile(filepath.Join("testdata", "valid", "rbc-deposit.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "deposit.yaml"), spec, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { parser.RegisterSet(SetName, nil) })
+
+	meta := parser.EmailMeta{Subject: "Deposit Notice", Text: "RBC Royal Bank"}
+
+	count, err := Reload(dir)
+	if err != nil || count != 1 {
+		t.Fatalf("Reload = %d, %v; want 1 parser", count, err)
+	}
+	if p := parser.Find(meta); parser.Name(p) != "rbc-deposit-yaml" {
+		t.Errorf("Find = %s; want the reloaded parser", parser.Name(p))
+	}
+
+	if err := os.Remove(filepath.Join(dir, "deposit.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := Reload(dir); err != nil || count != 0 {
+		t.Fatalf("Reload = %d, %v; want 0 parsers", count, err)
+	}
+	if p := parser.Find(meta); p != nil {
+		t.Errorf("Find = %s; removed parser is still registered", parser.Name(p))
+	}
+}
+
+// a typo in a file that loaded before keeps its parser working until it is fixed
+func TestReloadKeepsInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deposit.yaml")
+	spec, err := os.ReadFile(filepath.Join("testdata", "valid", "rbc-deposit.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, spec, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { parser.RegisterSet(SetName, nil) })
+
+	meta := parser.EmailMeta{Subject: "Deposit Notice", Text: "RBC Royal Bank"}
+	if _, err := Reload(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, append(spec, []byte("\nnot_a_setting: true\n")...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	count, err := Reload(dir)
+	if count != 1 || err == nil || !strings.Contains(err.Error(), "still using") {
+		t.Errorf("Reload = %d, %v; want the old parser kept and the error reported", count, err)
+	}
+	if p := parser.Find(meta); parser.Name(p) != "rbc-deposit-yaml" {
+		t.Errorf("Find = %s; want the parser loaded before", parser.Name(p))
+	}
+
+	// a file that never loaded has nothing to keep
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("not_a_setting: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := Reload(dir); count != 1 {
+		t.Errorf("Reload = %d; want only the kept parser", count)
+	}
+
+	// once removed the file is forgotten
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ := Reload(dir); count != 0 {
+		t.Errorf("Reload = %d; want the removed file's parser dropped", count)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	spec := Spec{
+		ID: "mybank-purchase", Bank: "mybank", Direction: domain.Out, Priority: 5,
+		Match:       MatchSpec{From: []string{"@alerts.mybank.com"}},
+		Fields:      map[string]Field{"amount": {Regex: `\$([0-9.]+)`}, "txdate": {Regex: `on (.+)`}},
+		Description: "purchase",
+	}
+	p, errs := compile("mybank.yaml", spec)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	info := parser.Describe(parser.AsMulti(p))
+	if info.ID != "mybank-purchase" || info.Priority != 5 || info.Version != 1 ||
+		len(info.Domains) != 1 || info.Domains[0] != "alerts.mybank.com" {
+		t.Errorf("Describe = %+v", info)
+	}
+
+	spec.Match.From = []string{"@alerts.mybank.com", "mybank"}
+	if p, _ = compile("mybank.yaml", spec); len(p.Describe().Domains) != 0 {
+		t.Errorf("Domains = %v; a plain substring must keep the parser tried for every sender", p.Describe().Domains)
+	}
+}
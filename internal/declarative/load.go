@@ -0,0 +1,149 @@
+package declarative
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"null-email-parser/internal/parser"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetName is the parser.RegisterSet name declarative parsers are registered under
+const SetName = "declarative"
+
+// extensions of spec files, yaml is a superset of json so one decoder reads both
+var extensions = []string{".yaml", ".yml", ".json"}
+
+// LoadFile reads and validates a single spec file
+func LoadFile(path string) (*Parser, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec Spec
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true) // a typo in a setting should not silently match every email
+	if err := dec.Decode(&spec); err != nil && !errors.Is(err, io.EOF) {
+		return nil, &ValidationError{File: path, Msg: fmt.Sprintf("invalid syntax: %v", err)}
+	}
+
+	p, errs := compile(path, spec)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return p, nil
+}
+
+// LoadDir loads every spec file in dir. files that fail validation are skipped and
+// reported in the returned error, the valid ones are still returned
+func LoadDir(dir string) ([]*Parser, error) {
+	files, err := loadFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return collect(files)
+}
+
+// file is a spec file and what loading it gave
+type file struct {
+	path   string
+	parser *Parser
+	err    error
+}
+
+// loadFiles loads every spec file in dir, in directory order
+func loadFiles(dir string) ([]file, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading parser directory: %w", err)
+	}
+
+	var files []file
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || !slices.Contains(extensions, ext) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		p, err := LoadFile(path)
+		files = append(files, file{path: path, parser: p, err: err})
+	}
+	return files, nil
+}
+
+// collect returns the parsers of files and their errors, skipping parsers whose id
+// an earlier file already uses
+func collect(files []file) ([]*Parser, error) {
+	var (
+		parsers []*Parser
+		errs    []error
+		ids     = map[string]string{}
+	)
+	for _, f := range files {
+		if f.err != nil {
+			errs = append(errs, f.err)
+		}
+		if f.parser == nil {
+			continue
+		}
+
+		if other, dup := ids[f.parser.Name()]; dup {
+			errs = append(errs, &ValidationError{File: f.path, Path: "id", Msg: fmt.Sprintf("%q is already used by %s", f.parser.Name(), other)})
+			continue
+		}
+		ids[f.parser.Name()] = f.path
+
+		parsers = append(parsers, f.parser)
+	}
+
+	return parsers, errors.Join(errs...)
+}
+
+var (
+	reloadMu sync.Mutex
+	loaded   = map[string]*Parser{} // file to the parser last registered from it
+)
+
+// Reload loads dir and replaces the declarative parsers in the registry with its
+// content. a file that no longer loads keeps the parser loaded from it before, so a
+// typo does not stop a bank from being parsed. it returns the number of parsers
+// registered along with any validation errors
+func Reload(dir string) (int, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	files, err := loadFiles(dir)
+	if err != nil {
+		// the directory itself is unreadable, keep what is registered
+		return 0, err
+	}
+
+	for i, f := range files {
+		if old := loaded[f.path]; f.err != nil && old != nil {
+			files[i].parser = old
+			files[i].err = errors.Join(f.err, fmt.Errorf("%s: still using the definition of %q loaded before", f.path, old.Name()))
+		}
+	}
+	parsers, err := collect(files)
+
+	loaded = map[string]*Parser{}
+	set := make([]parser.MultiParser, 0, len(parsers))
+	for _, p := range parsers {
+		loaded[p.File()] = p
+		set = append(set, parser.AsMulti(p))
+	}
+	parser.RegisterSet(SetName, set)
+
+	return len(parsers), err
+}
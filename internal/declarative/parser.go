@@ -0,0 +1,139 @@
+package declarative
+
+import (
+	"errors"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"null-email-parser/internal/domain"
+	"null-email-parser/internal/parser"
+)
+
+// Parser is a parser.Parser built from a Spec
+type Parser struct {
+	spec Spec
+	file string
+
+	from, subject, body []matcher
+
+	patterns    map[string]*regexp.Regexp // fields extracted from the text
+	labels      map[string]string         // fields extracted from the html by label
+	description *template.Template
+}
+
+// Name returns the spec id, used in logs and errors
+func (p *Parser) Name() string { return p.spec.ID }
+
+// Describe identifies the parser in the registry. when every from entry is a plain
+// "@domain", the parser is only tried for emails from those domains
+func (p *Parser) Describe() parser.Info {
+	info := parser.Info{ID: p.spec.ID, Bank: p.spec.Bank, Version: p.spec.Version, Priority: p.spec.Priority}
+
+	var domains []string
+	for _, m := range p.from {
+		domain, ok := strings.CutPrefix(m.substr, "@")
+		if m.re != nil || !ok || !strings.Contains(domain, ".") {
+			return info // not a domain, every sender has to be tried
+		}
+		domains = append(domains, domain)
+	}
+	info.Domains = domains
+	return info
+}
+
+// File returns the file the parser was loaded from
+func (p *Parser) File() string { return p.file }
+
+// Spec returns the definition the parser was built from
+func (p *Parser) Spec() Spec { return p.spec }
+
+func (p *Parser) Match(m parser.EmailMeta) bool {
+	from := m.From
+	if m.FromAddress != nil {
+		from = m.FromAddress.Address
+	}
+
+	return matchAny(p.from, from) &&
+		matchAny(p.subject, m.Subject) &&
+		matchAny(p.body, m.Text)
+}
+
+func (p *Parser) Parse(m parser.EmailMeta) (*domain.Transaction, error) {
+	fields := make(parser.Fields, len(p.spec.Fields))
+
+	for _, key := range slices.Sorted(maps.Keys(p.spec.Fields)) {
+		field, err := p.extract(m, key)
+		if err != nil {
+			if !p.spec.Fields[key].Optional {
+				return nil, p.named(err)
+			}
+			field = parser.Field{Pattern: field.Pattern, Offset: -1}
+		}
+		fields[key] = field
+	}
+
+	values := make(map[string]string, len(fields))
+	for key := range fields {
+		values[key] = strings.TrimSpace(fields.Get(key))
+	}
+
+	var desc strings.Builder
+	if err := p.description.Execute(&desc, values); err != nil {
+		return nil, p.named(&parser.ParseError{Field: "description", Pattern: p.spec.Description, Err: err})
+	}
+
+	txn, err := parser.BuildTransaction(
+		m,
+		fields,
+		p.spec.Bank,
+		p.spec.Currency,
+		p.spec.Direction,
+		strings.TrimSpace(desc.String()),
+	)
+	if err != nil {
+		return nil, p.named(err)
+	}
+
+	return txn, nil
+}
+
+// extract finds one field, by label in the html and/or by regex
+func (p *Parser) extract(m parser.EmailMeta, key string) (parser.Field, error) {
+	text := m.Text
+	var found parser.Field
+
+	if label, ok := p.labels[key]; ok {
+		got, err := parser.ExtractHTMLFields(m.HTML, map[string]string{key: label})
+		if err != nil {
+			return parser.Field{Pattern: label}, err
+		}
+		found = got[key]
+		text = found.Value
+	}
+
+	if re, ok := p.patterns[key]; ok {
+		got, err := parser.ExtractFields(text, map[string]*regexp.Regexp{key: re})
+		if err != nil {
+			return parser.Field{Pattern: re.String()}, err
+		}
+		if found.Pattern != "" {
+			// regex applied to a label value, the offset in the text is unknown
+			return parser.Field{Value: got.Get(key), Pattern: found.Pattern + " " + re.String(), Offset: -1}, nil
+		}
+		found = got[key]
+	}
+
+	return found, nil
+}
+
+// named fills in the parser name on parse errors
+func (p *Parser) named(err error) error {
+	var perr *parser.ParseError
+	if errors.As(err, &perr) && perr.Parser == "" {
+		perr.Parser = p.Name()
+	}
+	return err
+}
@@ -0,0 +1,219 @@
+// Package declarative builds parsers from YAML or JSON definitions, so banks with simple
+// notification emails can be supported without writing Go
+package declarative
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"null-email-parser/internal/domain"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the file format of a declarative parser
+//
+//	id: mybank-purchase
+//	bank: mybank
+//	currency: CAD
+//	direction: out
+//	match:
+//	  from: ["@alerts.mybank.com"]
+//	  subject: ["You made a purchase"]
+//	fields:
+//	  account: '(\*+\d{4})'
+//	  amount: '\$([0-9,]+\.\d{2})'
+//	  txdate: '([A-Za-z]+ \d{1,2}, \d{4})'
+//	  desc:
+//	    label: "Merchant:"
+//	description: "{{.desc}}"
+type Spec struct {
+	ID          string           `yaml:"id"`
+	Bank        string           `yaml:"bank"`
+	Version     int              `yaml:"version"`  // bump when the definition changes for a new template
+	Priority    int              `yaml:"priority"` // higher wins when several parsers match, default 0
+	Currency    string           `yaml:"currency"`
+	Direction   domain.Direction `yaml:"direction"`
+	Match       MatchSpec        `yaml:"match"`
+	Fields      map[string]Field `yaml:"fields"`
+	Description string           `yaml:"description"` // text/template over the field values
+}
+
+// MatchSpec selects the emails a parser handles. each list matches if any of its entries
+// does, and every non-empty list must match. entries are case-insensitive substrings,
+// or regular expressions when written as /.../
+type MatchSpec struct {
+	From    []string `yaml:"from"` // sender address, e.g. "@rbc.com"
+	Subject []string `yaml:"subject"`
+	Body    []string `yaml:"body"`
+}
+
+// Field describes how to extract one value, either with a regex over the text
+// or with a label in the html body. with both, the regex is applied to the value
+// found next to the label. a plain string is shorthand for a regex
+type Field struct {
+	Regex    string `yaml:"regex"`
+	Label    string `yaml:"label"`
+	Optional bool   `yaml:"optional"`
+}
+
+func (f *Field) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		f.Regex = node.Value
+		return nil
+	}
+
+	type plain Field
+	return node.Decode((*plain)(f))
+}
+
+// required fields are needed by parser.BuildTransaction
+var requiredFields = []string{"amount", "txdate"}
+
+// ValidationError is a problem with one setting of a spec file
+type ValidationError struct {
+	File string
+	Path string // e.g. "fields.amount.regex"
+	Msg  string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.File, e.Path, e.Msg)
+}
+
+// compile validates a spec and turns it into a parser. every problem found is
+// returned, not just the first
+func compile(file string, s Spec) (*Parser, []error) {
+	var errs []error
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, &ValidationError{File: file, Path: path, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if s.ID == "" {
+		fail("id", "is required")
+	}
+	if s.Bank == "" {
+		fail("bank", "is required")
+	}
+	if s.Version < 0 {
+		fail("version", "must not be negative")
+	}
+	if s.Currency == "" {
+		s.Currency = "CAD"
+	}
+	if len(s.Currency) != 3 {
+		fail("currency", "must be an ISO 4217 code, got %q", s.Currency)
+	}
+	s.Currency = strings.ToUpper(s.Currency)
+	if s.Direction != domain.In && s.Direction != domain.Out {
+		fail("direction", "must be %q or %q, got %q", domain.In, domain.Out, s.Direction)
+	}
+
+	p := &Parser{spec: s, file: file, patterns: map[string]*regexp.Regexp{}, labels: map[string]string{}}
+
+	var err error
+	if p.from, err = compileMatchers(s.Match.From); err != nil {
+		fail("match.from", "%v", err)
+	}
+	if p.subject, err = compileMatchers(s.Match.Subject); err != nil {
+		fail("match.subject", "%v", err)
+	}
+	if p.body, err = compileMatchers(s.Match.Body); err != nil {
+		fail("match.body", "%v", err)
+	}
+	if len(p.from)+len(p.subject)+len(p.body) == 0 {
+		fail("match", "needs at least one of from, subject or body, otherwise every email matches")
+	}
+
+	for _, key := range requiredFields {
+		if f, ok := s.Fields[key]; !ok {
+			fail("fields."+key, "is required")
+		} else if f.Optional {
+			fail("fields."+key+".optional", "cannot be set, the field is required")
+		}
+	}
+
+	for key, f := range s.Fields {
+		path := "fields." + key
+		if f.Regex == "" && f.Label == "" {
+			fail(path, "needs a regex or a label")
+			continue
+		}
+
+		if f.Regex != "" {
+			re, err := regexp.Compile(f.Regex)
+			if err != nil {
+				fail(path+".regex", "invalid regex: %v", err)
+				continue
+			}
+			if re.NumSubexp() < 1 {
+				fail(path+".regex", "needs a capture group around the value")
+				continue
+			}
+			p.patterns[key] = re
+		}
+		if f.Label != "" {
+			p.labels[key] = f.Label
+		}
+	}
+
+	if s.Description == "" {
+		fail("description", "is required, use a fixed text or a template like {{.desc}}")
+	} else if p.description, err = template.New(s.ID).Option("missingkey=zero").Parse(s.Description); err != nil {
+		fail("description", "invalid template: %v", err)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return p, nil
+}
+
+// matcher is one entry of a match list
+type matcher struct {
+	substr string
+	re     *regexp.Regexp
+}
+
+func (m matcher) match(s string) bool {
+	if m.re != nil {
+		return m.re.MatchString(s)
+	}
+	return strings.Contains(strings.ToLower(s), m.substr)
+}
+
+func compileMatchers(entries []string) ([]matcher, error) {
+	out := make([]matcher, 0, len(entries))
+	for _, e := range entries {
+		if len(e) > 2 && strings.HasPrefix(e, "/") && strings.HasSuffix(e, "/") {
+			re, err := regexp.Compile(e[1 : len(e)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex %q: %w", e, err)
+			}
+			out = append(out, matcher{re: re})
+			continue
+		}
+		if e == "" {
+			return nil, fmt.Errorf("empty entry")
+		}
+		out = append(out, matcher{substr: strings.ToLower(e)})
+	}
+	return out, nil
+}
+
+func matchAny(matchers []matcher, s string) bool {
+	if len(matchers) == 0 {
+		return true
+	}
+	for _, m := range matchers {
+		if m.match(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package domain
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number with up to nine fractional digits, the
+// precision of google.type.Money. like Money it is units plus nanos of the same sign,
+// so it converts both ways without loss. the zero value is 0
+type Decimal struct {
+	units int64
+	nanos int32
+}
+
+const nanosPerUnit = 1_000_000_000
+
+// ErrDecimal is returned for strings that are not decimal numbers
+var ErrDecimal = errors.New("invalid decimal")
+
+// NewDecimal returns units + nanos/1e9. the signs of units and nanos must agree
+func NewDecimal(units int64, nanos int32) (Decimal, error) {
+	if nanos <= -nanosPerUnit || nanos >= nanosPerUnit || units > 0 && nanos < 0 || units < 0 && nanos > 0 {
+		return Decimal{}, fmt.Errorf("%w: units %d and nanos %d", ErrDecimal, units, nanos)
+	}
+	return Decimal{units: units, nanos: nanos}, nil
+}
+
+// MustDecimal is ParseDecimal for constants, it panics on invalid input
+func MustDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// ParseDecimal reads "123", "-0.5" or "1234.567890123". there is no exponent and
+// no thousands separator, see parser.ParseMoney for amounts as written in emails
+func ParseDecimal(s string) (Decimal, error) {
+	raw := s
+	negative := strings.HasPrefix(s, "-")
+	if s != "" && (s[0] == '-' || s[0] == '+') {
+		s = s[1:] // one sign, "-+5" is not a number
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" && fraction == "" || strings.Trim(whole+fraction, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrDecimal, raw)
+	}
+	if len(fraction) > 9 {
+		return Decimal{}, fmt.Errorf("%w: %q has more than 9 decimals", ErrDecimal, raw)
+	}
+
+	sign := ""
+	if negative {
+		sign = "-" // parsed with the digits, -9223372036854775808 has no positive
+	}
+	var d Decimal
+	if whole != "" {
+		units, err := strconv.ParseInt(sign+whole, 10, 64)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("%w: %q out of range", ErrDecimal, raw)
+		}
+		d.units = units
+	}
+	if fraction != "" {
+		nanos, _ := strconv.ParseInt(sign+fraction+strings.Repeat("0", 9-len(fraction)), 10, 32)
+		d.nanos = int32(nanos)
+	}
+	return d, nil
+}
+
+// Units is the whole part, as google.type.Money units
+func (d Decimal) Units() int64 { return d.units }
+
+// Nanos is the fractional part in billionths, as google.type.Money nanos
+func (d Decimal) Nanos() int32 { return d.nanos }
+
+// IsZero reports whether d is 0
+func (d Decimal) IsZero() bool { return d.units == 0 && d.nanos == 0 }
+
+// IsNegative reports whether d is below 0
+func (d Decimal) IsNegative() bool { return d.units < 0 || d.nanos < 0 }
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal { return Decimal{units: -d.units, nanos: -d.nanos} }
+
+// Abs returns |d|
+func (d Decimal) Abs() Decimal {
+	if d.IsNegative() {
+		return d.Neg()
+	}
+	return d
+}
+
+// Cmp returns -1, 0 or 1 as d is below, equal to or above e
+func (d Decimal) Cmp(e Decimal) int {
+	return cmp.Or(cmp.Compare(d.units, e.units), cmp.Compare(d.nanos, e.nanos))
+}
+
+// Float64 is d as a float, for the fields null-core keeps as floats such as the exchange rate
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// Mul returns d × e rounded half away from zero to places decimals, at most 9
+func (d Decimal) Mul(e Decimal, places int) (Decimal, error) {
+	n := new(big.Int).Mul(d.big(), e.big())
+	return fromBig(n, big.NewInt(nanosPerUnit), places)
+}
+
+// Div returns d ÷ e rounded half away from zero to places decimals, at most 9
+func (d Decimal) Div(e Decimal, places int) (Decimal, error) {
+	if e.IsZero() {
+		return Decimal{}, fmt.Errorf("%w: division of %s by zero", ErrDecimal, d)
+	}
+	n := new(big.Int).Mul(d.big(), big.NewInt(nanosPerUnit))
+	return fromBig(n, e.big(), places)
+}
+
+// big returns d in nanos
+func (d Decimal) big() *big.Int {
+	n := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(nanosPerUnit))
+	return n.Add(n, big.NewInt(int64(d.nanos)))
+}
+
+// fromBig returns num/den nanos rounded to places decimals
+func fromBig(num, den *big.Int, places int) (Decimal, error) {
+	places = min(max(places, 0), 9)
+	step := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(9-places)), nil)
+
+	// rounding is done on absolute values, then the sign is put back
+	negative := num.Sign()*den.Sign() < 0
+	den = new(big.Int).Mul(new(big.Int).Abs(den), step)
+	q, r := new(big.Int).QuoRem(new(big.Int).Abs(num), den, new(big.Int))
+	if r.Lsh(r, 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	q.Mul(q, step)
+	if negative {
+		q.Neg(q)
+	}
+
+	units, nanos := new(big.Int).QuoRem(q, big.NewInt(nanosPerUnit), new(big.Int))
+	if !units.IsInt64() {
+		return Decimal{}, fmt.Errorf("%w: result out of range", ErrDecimal)
+	}
+	return Decimal{units: units.Int64(), nanos: int32(nanos.Int64())}, nil
+}
+
+// String formats d with at least two decimals, "39.50", "-0.125" or "1000.00"
+func (d Decimal) String() string {
+	sign := ""
+	if d.IsNegative() {
+		sign = "-"
+	}
+	units, nanos := d.units, int64(d.nanos)
+	if units < 0 || nanos < 0 {
+		units, nanos = -units, -nanos // uint64 below gets math.MinInt64 right too
+	}
+
+	fraction := strings.TrimRight(fmt.Sprintf("%09d", nanos), "0")
+	if len(fraction) < 2 {
+		fraction += strings.Repeat("0", 2-len(fraction))
+	}
+	return sign + strconv.FormatUint(uint64(units), 10) + "." + fraction
+}
+
+// MarshalText writes d as String does, json keeps it a string and exact
+func (d Decimal) MarshalText() ([]byte, error) { return []byte(d.String()), nil }
+
+// UnmarshalText reads d as ParseDecimal does
+func (d *Decimal) UnmarshalText(text []byte) error {
+	parsed, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in     string
+		units  int64
+		nanos  int32
+		string string
+	}{
+		{"39.50", 39, 500_000_000, "39.50"},
+		{"39.5", 39, 500_000_000, "39.50"},
+		{"0.1", 0, 100_000_000, "0.10"},
+		{"1000", 1000, 0, "1000.00"},
+		{"-0.125", 0, -125_000_000, "-0.125"},
+		{"-12.01", -12, -10_000_000, "-12.01"},
+		{"0.999999999", 0, 999_999_999, "0.999999999"},
+		{".5", 0, 500_000_000, "0.50"},
+		{"+7", 7, 0, "7.00"},
+		{"-0", 0, 0, "0.00"},
+		{"9223372036854775807.999999999", math.MaxInt64, 999_999_999, "9223372036854775807.999999999"},
+		{"-9223372036854775808", math.MinInt64, 0, "-9223372036854775808.00"},
+	}
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.in)
+		if err != nil {
+			t.Errorf("ParseDecimal(%q): %v", tt.in, err)
+			continue
+		}
+		if d.Units() != tt.units || d.Nanos() != tt.nanos || d.String() != tt.string {
+			t.Errorf("ParseDecimal(%q) = %d, %d, %q; want %d, %d, %q", tt.in, d.Units(), d.Nanos(), d, tt.units, tt.nanos, tt.string)
+		}
+	}
+
+	for _, bad := range []string{"", "-", ".", "1.2.3", "1,000", "1e3", "abc", "0.1234567891", "9223372036854775808", "-+5", "+-5", "--5", "++5"} {
+		if _, err := ParseDecimal(bad); !errors.Is(err, ErrDecimal) {
+			t.Errorf("ParseDecimal(%q) = %v; want ErrDecimal", bad, err)
+		}
+	}
+}
+
+// every units and nanos pair google.type.Money allows comes back unchanged
+func TestDecimalRoundTrip(t *testing.T) {
+	pairs := []struct {
+		units int64
+		nanos int32
+	}{
+		{39, 500_000_000},
+		{0, 1},
+		{0, -1},
+		{-1, -999_999_999},
+		{1, 999_999_999},
+		{math.MaxInt64, 999_999_999},
+		{math.MinInt64, -999_999_999},
+	}
+	for _, p := range pairs {
+		d, err := NewDecimal(p.units, p.nanos)
+		if err != nil {
+			t.Fatalf("NewDecimal(%d, %d): %v", p.units, p.nanos, err)
+		}
+		parsed, err := ParseDecimal(d.String())
+		if err != nil || parsed != d || parsed.Units() != p.units || parsed.Nanos() != p.nanos {
+			t.Errorf("ParseDecimal(%q) = %d, %d, %v; want %d, %d", d, parsed.Units(), parsed.Nanos(), err, p.units, p.nanos)
+		}
+
+		data, err := json.Marshal(struct{ Amount Decimal }{d})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back struct{ Amount Decimal }
+		if err := json.Unmarshal(data, &back); err != nil || back.Amount != d {
+			t.Errorf("json round trip of %s = %s, %v (%s)", d, back.Amount, err, data)
+		}
+	}
+
+	for _, bad := range [][2]int64{{1, -1}, {-1, 1}, {0, 1_000_000_000}, {0, -1_000_000_000}} {
+		if _, err := NewDecimal(bad[0], int32(bad[1])); !errors.Is(err, ErrDecimal) {
+			t.Errorf("NewDecimal(%d, %d) = %v; want ErrDecimal", bad[0], bad[1], err)
+		}
+	}
+}
+
+func TestDecimalCmp(t *testing.T) {
+	a, b := MustDecimal("-0.5"), MustDecimal("0.25")
+	if a.Cmp(b) != -1 || b.Cmp(a) != 1 || a.Cmp(a) != 0 || !a.IsNegative() || a.Neg() != MustDecimal("0.5") || a.Abs() != MustDecimal("0.5") {
+		t.Errorf("comparisons of %s and %s are wrong", a, b)
+	}
+	if !MustDecimal("0.00").IsZero() || MustDecimal("0.000000001").IsZero() {
+		t.Error("IsZero is wrong")
+	}
+}
+
+func TestDecimalMulDiv(t *testing.T) {
+	tests := []struct {
+		op        string
+		a, b      string
+		places    int
+		want      string
+		wantError bool
+	}{
+		{"mul", "12.00", "1.3767", 2, "16.52", false},
+		{"mul", "12.005", "1", 2, "12.01", false},
+		{"mul", "-12.005", "1", 2, "-12.01", false},
+		{"mul", "0.1", "0.2", 9, "0.02", false},
+		{"mul", "9223372036854775807", "2", 2, "", true},
+		{"div", "16.52", "12.00", 6, "1.376667", false},
+		{"div", "-1", "3", 4, "-0.3333", false},
+		{"div", "2", "3", 0, "1.00", false},
+		{"div", "1", "0", 2, "", true},
+	}
+	for _, tt := range tests {
+		a, b := MustDecimal(tt.a), MustDecimal(tt.b)
+		var got Decimal
+		var err error
+		if tt.op == "mul" {
+			got, err = a.Mul(b, tt.places)
+		} else {
+			got, err = a.Div(b, tt.places)
+		}
+		if tt.wantError {
+			if err == nil {
+				t.Errorf("%s %s %s = %s; want an error", tt.a, tt.op, tt.b, got)
+			}
+			continue
+		}
+		if err != nil || got.String() != tt.want {
+			t.Errorf("%s %s %s = %s, %v; want %s", tt.a, tt.op, tt.b, got, err, tt.want)
+		}
+	}
+}
@@ -9,6 +9,35 @@ const (
 	Out Direction = "out"
 )
 
+// AccountKind is the kind of account a transaction went through, as told by the email
+type AccountKind string
+
+const (
+	UnknownAccount AccountKind = ""
+	CreditCard     AccountKind = "credit_card"
+	Chequing       AccountKind = "chequing"
+	Savings        AccountKind = "savings"
+	LineOfCredit   AccountKind = "line_of_credit"
+	Investment     AccountKind = "investment"
+)
+
+// Label is the kind as people write it, "Credit Card", "" when unknown
+func (k AccountKind) Label() string {
+	switch k {
+	case CreditCard:
+		return "Credit Card"
+	case Chequing:
+		return "Chequing"
+	case Savings:
+		return "Savings"
+	case LineOfCredit:
+		return "Line of Credit"
+	case Investment:
+		return "Investment"
+	}
+	return ""
+}
+
 type Transaction struct {
 	ID        string // serial primary key (ignored on insert)
 	EmailID   string // message ID from Mailpit or similar
@@ -17,16 +46,30 @@ type Transaction struct {
 	TxDate      time.Time // date extracted from email body
 	TxBank      string    // e.g. "rbc"
 	TxAccount   string    // e.g. "****1234"
-	TxAmount    float64
+	TxAmount    Decimal
 	TxDirection Direction // "in" or "out"
 	TxDesc      string    // raw transaction description (parsed from email)
 	TxCurrency  string    // e.g. "CAD"
 
+	AccountKind     AccountKind // e.g. CreditCard, UnknownAccount when the email does not say
+	AccountCurrency string      // currency the account is kept in, e.g. "CAD"
+
 	Category  string // to be AI-assigned later
 	Merchant  string // inferred or parsed from description
 	UserNotes string // manually entered by user later
 
-	ForeignAmount   *float64
+	ForeignAmount   *Decimal
 	ForeignCurrency *string
-	ExchangeRate    *float64
+	ExchangeRate    *Decimal
+
+	Provenance map[string]FieldSource // where each parsed field came from, for diagnostics
+
+	LowConfidence bool // guessed by the heuristic extractor rather than parsed by a bank parser
+}
+
+// FieldSource records which pattern produced a parsed field and where in the text
+type FieldSource struct {
+	Pattern string
+	Offset  int    // byte offset in the email text, -1 if not applicable
+	Note    string // what was guessed, e.g. "year inferred", empty when nothing was
 }
@@ -0,0 +1,30 @@
+package drift
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Register adds the drift routes of the admin api to mux:
+//
+//	GET  /drift                        the raised alerts
+//	POST /drift/{parser}/acknowledge   clear the alert of a parser once its template is checked
+//
+// it does no authentication, see admin.RequireKey
+func (t *Tracker) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /drift", func(w http.ResponseWriter, r *http.Request) {
+		alerts := t.Alerts()
+		if alerts == nil {
+			alerts = []Alert{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(alerts)
+	})
+	mux.HandleFunc("POST /drift/{parser}/acknowledge", func(w http.ResponseWriter, r *http.Request) {
+		if err := t.Acknowledge(r.PathValue("parser")); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,238 @@
+// Package drift notices when a bank changes the template of an email a parser handles.
+// every parser learns the fingerprints of the emails it parses; a parser is flagged
+// when the emails it matches keep failing to parse, or when a template it has not
+// seen before becomes established
+package drift
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"null-email-parser/internal/parser"
+)
+
+// Reason a parser is flagged
+type Reason string
+
+const (
+	Failing  Reason = "failing"  // consecutive parse failures
+	Template Reason = "template" // a new template became established
+)
+
+// Defaults of a Tracker
+const (
+	DefaultFailures = 3 // consecutive failures that flag a parser
+	DefaultSettle   = 3 // parses with a new fingerprint that establish it
+)
+
+// Alert is the drift state of a parser
+type Alert struct {
+	Parser      string    `json:"parser"`
+	Version     int       `json:"version"`
+	Reason      Reason    `json:"reason,omitempty"` // "" once the parser is fine again
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	Since       time.Time `json:"since,omitzero"`
+}
+
+// Drifted reports whether the alert is raised
+func (a Alert) Drifted() bool { return a.Reason != "" }
+
+// state is what is known about one parser version
+type state struct {
+	Version  int            `json:"version"`
+	Known    map[string]int `json:"known"`           // fingerprints of parsed emails, with counts
+	Novel    map[string]int `json:"novel,omitempty"` // fingerprints seen since the baseline was set
+	Failures int            `json:"failures"`        // consecutive
+	Alert    Alert          `json:"alert"`
+}
+
+// Tracker records outcomes per parser and raises alerts. its zero value is not
+// usable, see New
+type Tracker struct {
+	Failures int              // consecutive failures that flag a parser
+	Settle   int              // parses with a new fingerprint that establish it
+	Now      func() time.Time // clock, for tests
+
+	// OnChange is called when an alert is raised or cleared, with the number of
+	// parsers drifted now. it runs under the lock and must not call the tracker
+	OnChange func(a Alert, drifted int)
+
+	mu      sync.Mutex
+	parsers map[string]*state
+	path    string // where the state is saved, "" to keep it in memory
+}
+
+// metrics are published at /debug/vars when the metrics server runs
+var metrics = expvar.NewMap("parser_drift")
+
+// New returns a tracker that saves its state to path, "" to keep it in memory. the
+// state saved by a previous run is loaded
+func New(path string) (*Tracker, error) {
+	t := &Tracker{
+		Failures: DefaultFailures,
+		Settle:   DefaultSettle,
+		Now:      time.Now,
+		parsers:  map[string]*state{},
+		path:     path,
+	}
+	if path == "" {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &t.parsers); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for id, s := range t.parsers {
+		publish(id, s)
+	}
+	return t, nil
+}
+
+// Observe records the outcome of parsing an email with fingerprint. it returns the
+// alert of the parser and whether it changed
+func (t *Tracker) Observe(info parser.Info, fingerprint string, parseErr error) (Alert, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var before Alert
+	s := t.parsers[info.ID]
+	if s != nil {
+		before = s.Alert
+	}
+	if s == nil || s.Version != info.Version {
+		// a new version was written for the current template, start over
+		s = &state{Version: info.Version, Known: map[string]int{}, Alert: Alert{Parser: info.ID, Version: info.Version}}
+		t.parsers[info.ID] = s
+	}
+
+	if parseErr != nil {
+		metrics.Add(info.ID+".failed", 1)
+		s.Failures++
+		if s.Failures >= t.Failures && !s.Alert.Drifted() {
+			s.Alert = Alert{Parser: info.ID, Version: info.Version, Reason: Failing, Fingerprint: fingerprint, Since: t.Now()}
+		}
+	} else {
+		metrics.Add(info.ID+".parsed", 1)
+		s.Failures = 0
+		if s.Alert.Reason == Failing {
+			s.Alert = Alert{Parser: info.ID, Version: info.Version}
+		}
+		t.learn(info, s, fingerprint)
+	}
+
+	publish(info.ID, s)
+
+	changed := s.Alert.Reason != before.Reason
+	if changed && t.OnChange != nil {
+		t.OnChange(s.Alert, t.drifted())
+	}
+	return s.Alert, changed, t.save()
+}
+
+// learn adds the fingerprint of a parsed email. the first ones form the baseline,
+// later unknown ones have to recur Settle times before they are known, which raises
+// a Template alert
+func (t *Tracker) learn(info parser.Info, s *state, fingerprint string) {
+	if _, ok := s.Known[fingerprint]; ok || len(s.Known) == 0 && len(s.Novel) == 0 {
+		s.Known[fingerprint]++
+		return
+	}
+
+	if s.Novel == nil {
+		s.Novel = map[string]int{}
+	}
+	s.Novel[fingerprint]++
+	if s.Novel[fingerprint] < t.Settle {
+		return
+	}
+
+	s.Known[fingerprint] = s.Novel[fingerprint]
+	delete(s.Novel, fingerprint)
+	if !s.Alert.Drifted() {
+		s.Alert = Alert{Parser: info.ID, Version: info.Version, Reason: Template, Fingerprint: fingerprint, Since: t.Now()}
+	}
+}
+
+// Acknowledge clears the alert of a parser, e.g. once the new template is checked
+func (t *Tracker) Acknowledge(id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.parsers[id]
+	if s == nil || !s.Alert.Drifted() {
+		return nil
+	}
+	s.Alert = Alert{Parser: id, Version: s.Version}
+	publish(id, s)
+	if t.OnChange != nil {
+		t.OnChange(s.Alert, t.drifted())
+	}
+	return t.save()
+}
+
+// drifted counts the parsers with a raised alert, under the lock
+func (t *Tracker) drifted() int {
+	n := 0
+	for _, s := range t.parsers {
+		if s.Alert.Drifted() {
+			n++
+		}
+	}
+	return n
+}
+
+// Alerts returns the raised alerts, by parser id
+func (t *Tracker) Alerts() []Alert {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out []Alert
+	for _, id := range slices.Sorted(maps.Keys(t.parsers)) {
+		if a := t.parsers[id].Alert; a.Drifted() {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func (t *Tracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(t.parsers, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
+
+// publish exposes the drift state of a parser as expvar metrics
+func publish(id string, s *state) {
+	drifted := new(expvar.Int)
+	if s.Alert.Drifted() {
+		drifted.Set(1)
+	}
+	metrics.Set(id+".drifted", drifted)
+
+	templates := new(expvar.Int)
+	templates.Set(int64(len(s.Known)))
+	metrics.Set(id+".templates", templates)
+}
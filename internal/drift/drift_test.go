@@ -0,0 +1,159 @@
+package drift
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"null-email-parser/internal/parser"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	info    = parser.Info{ID: "testbank.purchase", Bank: "testbank", Version: 1}
+	errTest = errors.New("amount not found")
+)
+
+func observe(t *testing.T, tr *Tracker, info parser.Info, fingerprint string, err error) (Alert, bool) {
+	t.Helper()
+	a, changed, saveErr := tr.Observe(info, fingerprint, err)
+	if saveErr != nil {
+		t.Fatal(saveErr)
+	}
+	return a, changed
+}
+
+func TestFailing(t *testing.T) {
+	tr, _ := New("")
+	var changes []Alert
+	tr.OnChange = func(a Alert, drifted int) { changes = append(changes, a) }
+
+	observe(t, tr, info, "a", nil)
+	for range DefaultFailures - 1 {
+		if a, _ := observe(t, tr, info, "a", errTest); a.Drifted() {
+			t.Fatalf("alert raised before %d failures", DefaultFailures)
+		}
+	}
+	a, changed := observe(t, tr, info, "b", errTest)
+	if !changed || a.Reason != Failing || a.Parser != info.ID || a.Fingerprint != "b" {
+		t.Errorf("alert = %+v, changed %v; want failing", a, changed)
+	}
+	if len(tr.Alerts()) != 1 {
+		t.Errorf("Alerts() = %+v", tr.Alerts())
+	}
+
+	if a, changed := observe(t, tr, info, "a", nil); !changed || a.Drifted() {
+		t.Errorf("alert = %+v after a parse; want it cleared", a)
+	}
+	if len(changes) != 2 {
+		t.Errorf("OnChange called %d times; want 2", len(changes))
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tr, _ := New("")
+
+	observe(t, tr, info, "old", nil)
+	observe(t, tr, info, "old", nil)
+	for range DefaultSettle - 1 {
+		if a, _ := observe(t, tr, info, "new", nil); a.Drifted() {
+			t.Fatal("alert raised before the new template settled")
+		}
+	}
+	a, changed := observe(t, tr, info, "new", nil)
+	if !changed || a.Reason != Template || a.Fingerprint != "new" || a.Parser != info.ID || a.Version != info.Version {
+		t.Errorf("alert = %+v; want a template alert", a)
+	}
+
+	if err := tr.Acknowledge(info.ID); err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := observe(t, tr, info, "new", nil); a.Drifted() {
+		t.Errorf("alert = %+v after acknowledging; the new template should be known", a)
+	}
+}
+
+func TestVersionResets(t *testing.T) {
+	tr, _ := New("")
+	for range DefaultFailures {
+		observe(t, tr, info, "a", errTest)
+	}
+
+	v2 := info
+	v2.Version = 2
+	if a, changed := observe(t, tr, v2, "b", nil); !changed || a.Drifted() {
+		t.Errorf("alert = %+v for a new version; want a fresh start", a)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drift.json")
+	tr, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range DefaultFailures {
+		observe(t, tr, info, "a", errTest)
+	}
+
+	reloaded, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alerts := reloaded.Alerts(); len(alerts) != 1 || alerts[0].Reason != Failing {
+		t.Errorf("reloaded alerts = %+v", alerts)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	fp := func(body string) string {
+		doc, err := html.Parse(strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Fingerprint(parser.EmailMeta{HTML: doc})
+	}
+
+	a := fp(`<table><tr><td>Amount</td><td>$1.00</td></tr></table>`)
+	b := fp(`<table><tr><td>Amount</td><td>$250.00</td></tr><tr><td>Merchant</td><td>SHOP</td></tr></table>`)
+	c := fp(`<div><p>Amount: $1.00</p></div>`)
+	if a != b {
+		t.Error("fingerprint depends on content or row count")
+	}
+	if a == c {
+		t.Error("different layouts have the same fingerprint")
+	}
+
+	text := func(s string) string { return Fingerprint(parser.EmailMeta{Text: s}) }
+	if text("A purchase of $1.00 was made at SHOP") != text("A purchase of $99.99 was made at OTHER STORE") {
+		t.Error("text fingerprint depends on amounts or merchants")
+	}
+	if text("A purchase of $1.00 was made") == text("A refund of $1.00 was issued") {
+		t.Error("different text templates have the same fingerprint")
+	}
+}
+
+func TestAdminAcknowledge(t *testing.T) {
+	tr, _ := New("")
+	for range DefaultFailures {
+		observe(t, tr, info, "a", errTest)
+	}
+	mux := http.NewServeMux()
+	tr.Register(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/drift", nil))
+	if !strings.Contains(w.Body.String(), `"parser":"`+info.ID+`"`) {
+		t.Errorf("GET /drift = %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/drift/"+info.ID+"/acknowledge", nil))
+	if w.Code != http.StatusNoContent || len(tr.Alerts()) != 0 {
+		t.Errorf("acknowledge = %d, alerts %+v", w.Code, tr.Alerts())
+	}
+}
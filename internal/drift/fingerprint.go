@@ -0,0 +1,63 @@
+package drift
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+
+	"null-email-parser/internal/parser"
+
+	"golang.org/x/net/html"
+)
+
+// Fingerprint describes the structure of an email, not its content: the set of
+// element paths of the html body ("html/body/table/tr/td"), or for text emails the
+// set of lines reduced to their lowercase words. two notifications of the same
+// template share a fingerprint whatever the amounts, dates or merchants in them
+func Fingerprint(meta parser.EmailMeta) string {
+	var shapes map[string]bool
+	if meta.HTML != nil {
+		shapes = htmlShapes(meta.HTML)
+	} else {
+		shapes = textShapes(meta.Text)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(slices.Sorted(maps.Keys(shapes)), "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+func htmlShapes(root *html.Node) map[string]bool {
+	shapes := map[string]bool{}
+	var walk func(n *html.Node, path string)
+	walk = func(n *html.Node, path string) {
+		if n.Type == html.ElementNode {
+			path += "/" + n.Data
+			shapes[path] = true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, path)
+		}
+	}
+	walk(root, "")
+	return shapes
+}
+
+func textShapes(text string) map[string]bool {
+	shapes := map[string]bool{}
+	for line := range strings.Lines(text) {
+		var words []string
+		for _, w := range strings.Fields(line) {
+			w = strings.TrimFunc(w, unicode.IsPunct)
+			if w != "" && strings.IndexFunc(w, func(r rune) bool { return !unicode.IsLower(r) }) < 0 {
+				words = append(words, w)
+			}
+		}
+		if len(words) > 0 {
+			shapes[strings.Join(words, " ")] = true
+		}
+	}
+	return shapes
+}
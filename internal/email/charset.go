@@ -0,0 +1,55 @@
+package email
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// wordDecoder decodes RFC 2047 encoded-words ("=?ISO-8859-1?Q?caf=E9?=") in any charset
+// known to the WHATWG encoding spec, not just the utf-8/iso-8859-1 mime handles itself
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q: %w", label, err)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// decodeHeader returns a copy of h with encoded-words decoded. values that fail to
+// decode are kept as received
+func decodeHeader(h mail.Header) mail.Header {
+	out := make(mail.Header, len(h))
+	for key, values := range h {
+		decoded := make([]string, len(values))
+		for i, v := range values {
+			if d, err := wordDecoder.DecodeHeader(v); err == nil {
+				decoded[i] = d
+			} else {
+				decoded[i] = v
+			}
+		}
+		out[key] = decoded
+	}
+	return out
+}
+
+// toUTF8 converts a text body to UTF-8. the charset comes from the Content-Type,
+// a BOM or an html <meta> tag, falling back to utf-8 or windows-1252 by sniffing
+func toUTF8(data []byte, contentType string) string {
+	enc, _, _ := charset.DetermineEncoding(data, contentType)
+
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+
+	return strings.TrimPrefix(string(out), "\ufeff")
+}
@@ -0,0 +1,83 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEncodedWordSubject(t *testing.T) {
+	raw := "Subject: =?ISO-8859-1?Q?Achat_=E0_la_caf=E9?=\r\n" +
+		"From: =?UTF-8?B?QmFucXVlIMOpY29ub21pcXVl?= <alerts@example.com>\r\n" +
+		"\r\n" +
+		"body\r\n"
+
+	msg, err := Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if got := msg.Header.Get("Subject"); got != "Achat à la café" {
+		t.Errorf("Subject = %q; want %q", got, "Achat à la café")
+	}
+	if got := msg.Header.Get("From"); got != "Banque économique <alerts@example.com>" {
+		t.Errorf("From = %q", got)
+	}
+}
+
+func TestParseCharsets(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{
+			name: "single part quoted-printable latin1",
+			raw: "Content-Type: text/plain; charset=iso-8859-1\r\n" +
+				"Content-Transfer-Encoding: quoted-printable\r\n" +
+				"\r\n" +
+				"Achat de 12,00 $ au caf=E9 =\r\n" +
+				"du coin\r\n",
+			want: "Achat de 12,00 $ au café du coin",
+		},
+		{
+			name: "multipart windows-1252",
+			raw: "Content-Type: multipart/alternative; boundary=b\r\n" +
+				"\r\n" +
+				"--b\r\n" +
+				"Content-Type: text/plain; charset=windows-1252\r\n" +
+				"Content-Transfer-Encoding: quoted-printable\r\n" +
+				"\r\n" +
+				"we=92re letting you know =96 thanks\r\n" +
+				"--b--\r\n",
+			want: "we’re letting you know – thanks",
+		},
+		{
+			name: "utf-16 with bom",
+			raw: "Content-Type: text/plain; charset=utf-16\r\n" +
+				"Content-Transfer-Encoding: base64\r\n" +
+				"\r\n" +
+				"//5jAGEAZgDpAA==\r\n",
+			want: "café",
+		},
+		{
+			name: "html meta charset",
+			raw: "Content-Type: text/html\r\n" +
+				"Content-Transfer-Encoding: quoted-printable\r\n" +
+				"\r\n" +
+				"<meta charset=3D\"iso-8859-1\"><p>caf=E9</p>\r\n",
+			want: "<meta charset=\"iso-8859-1\"><p>café</p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Parse([]byte("Subject: test\r\n" + tt.raw))
+			if err != nil {
+				t.Fatalf("Parse returned error: %v", err)
+			}
+			if got := strings.TrimSpace(msg.Text); got != tt.want {
+				t.Errorf("Text = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,127 +7,255 @@ import (
 	"io"
 	"mime"
 	"mime/multipart"
+	"mime/quotedprintable"
 	"net/mail"
 	"strings"
 )
 
-// ParseMessage parses raw email data and returns both the message and plain text content
-func ParseMessage(raw []byte) (*mail.Message, string, error) {
+// Message is a decoded email as parsers should see it
+type Message struct {
+	Header mail.Header // headers of the message itself
+	Text   string      // decoded text content, text/plain preferred over text/html
+	HTML   string      // decoded text/html part, if any
+
+	Parts       []Part       // every text/plain and text/html part, in order
+	Attachments []Attachment // every other part, except the attached message this one was unwrapped from
+
+	// Outer is the message that carried this one when it was forwarded,
+	// e.g. as a message/rfc822 attachment. nil for messages received directly
+	Outer *Message
+
+	// Attached is true when this message was a message/rfc822 attachment of Outer
+	// rather than an inline forward in its text
+	Attached bool
+
+	// FloatingDate is true when the Date of an inline forward was written without a
+	// zone. its clock time was read in the zone of Outer, which may not be the sender's
+	FloatingDate bool
+}
+
+// Envelope returns the outermost message, i.e. the one actually delivered to us
+func (m *Message) Envelope() *Message {
+	env := m
+	for env.Outer != nil {
+		env = env.Outer
+	}
+	return env
+}
+
+// Parse parses raw email data. if the email is a forward, either inline or as an
+// attached message, the original message is returned with the wrapper available as Outer
+func Parse(raw []byte) (*Message, error) {
 	msg, err := mail.ReadMessage(bytes.NewReader(raw))
 	if err != nil {
-		return nil, "", fmt.Errorf("parsing email message: %w", err)
+		return nil, fmt.Errorf("parsing email message: %w", err)
 	}
 
 	content, err := extractTextContent(msg)
 	if err != nil {
-		return nil, "", fmt.Errorf("extracting text content: %w", err)
+		return nil, fmt.Errorf("extracting text content: %w", err)
+	}
+
+	m := &Message{
+		Header:      decodeHeader(msg.Header),
+		Text:        content.text,
+		HTML:        content.html,
+		Parts:       content.parts,
+		Attachments: content.attachments,
+	}
+	if content.attached != nil {
+		inner, err := Parse(content.attached)
+		if err == nil {
+			top := inner.Envelope()
+			top.Outer = m
+			top.Attached = true
+			return inner, nil
+		}
+		if m.Text == "" {
+			return nil, fmt.Errorf("parsing attached message: %w", err)
+		}
+		// the wrapper text is all there is, keep the message it failed to unwrap
+		m.Attachments = append(m.Attachments, Attachment{
+			ContentType: "message/rfc822",
+			Size:        len(content.attached),
+			raw:         content.attached,
+		})
+	}
+
+	// inline forwards, possibly forwarded more than once
+	for {
+		inner := unwrapForward(m)
+		if inner == nil {
+			return m, nil
+		}
+		m = inner
+	}
+}
+
+// ParseMessage parses raw email data and returns both the message and plain text content
+func ParseMessage(raw []byte) (*mail.Message, string, error) {
+	m, err := Parse(raw)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return msg, content, nil
+	return &mail.Message{Header: m.Header, Body: strings.NewReader(m.Text)}, m.Text, nil
+}
+
+// bodyContent is what was found while walking a message body
+type bodyContent struct {
+	text        string
+	html        string
+	attached    []byte // raw message/rfc822 part, if any
+	parts       []Part
+	attachments []Attachment
 }
 
 // extractTextContent extracts plain text content from a mail message
-func extractTextContent(msg *mail.Message) (string, error) {
+func extractTextContent(msg *mail.Message) (bodyContent, error) {
 	ct := msg.Header.Get("Content-Type")
 	if ct == "" {
 		// Simple text email, read body directly
-		body, err := io.ReadAll(msg.Body)
+		body, err := decodeBody(msg.Body, msg.Header.Get("Content-Transfer-Encoding"))
 		if err != nil {
-			return "", fmt.Errorf("reading simple email body: %w", err)
+			return bodyContent{}, fmt.Errorf("reading simple email body: %w", err)
 		}
-		return string(body), nil
+		text := toUTF8(body, "text/plain")
+		return bodyContent{text: text, parts: []Part{{ContentType: "text/plain", Text: text}}}, nil
 	}
 
 	mediaType, params, err := mime.ParseMediaType(ct)
 	if err != nil {
-		return "", fmt.Errorf("parsing Content-Type: %w", err)
+		return bodyContent{}, fmt.Errorf("parsing Content-Type: %w", err)
 	}
 
 	// Handle multipart emails
 	if strings.HasPrefix(mediaType, "multipart/") {
-		return extractMultipartText(msg.Body, params["boundary"])
+		var found bodyContent
+		if err := extractMultipartText(msg.Body, params["boundary"], &found); err != nil {
+			return bodyContent{}, err
+		}
+		return found.preferred()
 	}
 
-	// Handle single-part email with encoding
-	encoding := msg.Header.Get("Content-Transfer-Encoding")
-	var reader io.Reader = msg.Body
-	if strings.EqualFold(encoding, "base64") {
-		reader = base64.NewDecoder(base64.StdEncoding, msg.Body)
+	body, err := decodeBody(msg.Body, msg.Header.Get("Content-Transfer-Encoding"))
+	if err != nil {
+		return bodyContent{}, fmt.Errorf("reading encoded body: %w", err)
 	}
 
-	body, err := io.ReadAll(reader)
-	if err != nil {
-		return "", fmt.Errorf("reading encoded body: %w", err)
+	// the whole email is a wrapped message
+	if mediaType == "message/rfc822" {
+		return bodyContent{attached: body}, nil
+	}
+
+	text := toUTF8(body, ct)
+	parts := []Part{{ContentType: mediaType, Text: text}}
+	if mediaType == "text/html" {
+		return bodyContent{text: text, html: text, parts: parts}, nil
+	}
+
+	return bodyContent{text: text, parts: parts}, nil
+}
+
+// preferred picks the content parsers should see: an attached message wins over the
+// wrapper text, text/plain wins over text/html. the wrapper text is still kept, for
+// when the attached message can't be parsed
+func (c bodyContent) preferred() (bodyContent, error) {
+	if c.text == "" {
+		c.text = c.html
+	}
+	if c.attached != nil || c.text != "" {
+		return c, nil
 	}
 
-	return string(body), nil
+	return bodyContent{}, fmt.Errorf("no text/plain, text/html or message/rfc822 part found")
 }
 
-// extractMultipartText walks through multipart email to find text content
-func extractMultipartText(body io.Reader, boundary string) (string, error) {
+// extractMultipartText walks through multipart email to find text content and attachments
+func extractMultipartText(body io.Reader, boundary string, found *bodyContent) error {
 	mr := multipart.NewReader(body, boundary)
-	var htmlContent string
 
 	for {
 		part, err := mr.NextPart()
 		if err == io.EOF {
-			break
+			return nil
 		}
 		if err != nil {
-			return "", fmt.Errorf("reading multipart: %w", err)
+			return fmt.Errorf("reading multipart: %w", err)
 		}
 
 		partType := part.Header.Get("Content-Type")
-
-		// Prefer text/plain if available
-		if strings.HasPrefix(partType, "text/plain") {
-			return decodePart(part)
+		if partType == "" {
+			partType = "text/plain" // RFC 2046 default
+		}
+		mediaType, params, err := mime.ParseMediaType(partType)
+		if err != nil {
+			continue
 		}
 
-		// Store HTML content as fallback
-		if strings.HasPrefix(partType, "text/html") {
+		switch {
+		// Handle nested multipart
+		case strings.HasPrefix(mediaType, "multipart/"):
+			if nestedBoundary := params["boundary"]; nestedBoundary != "" {
+				// a broken nested part should not discard what was already found
+				_ = extractMultipartText(part, nestedBoundary, found)
+			}
+
+		// Forwarded as attachment, only the first one is unwrapped
+		case mediaType == "message/rfc822" && found.attached == nil:
 			if content, err := decodePart(part); err == nil {
-				htmlContent = content
+				found.attached = content
 			}
-		}
 
-		// Handle nested multipart
-		if strings.HasPrefix(partType, "multipart/") {
-			_, params, err := mime.ParseMediaType(partType)
+		case isAttachment(part, mediaType):
+			if a, err := newAttachment(part, mediaType, params); err == nil {
+				found.attachments = append(found.attachments, a)
+			}
+
+		default:
+			content, err := decodePart(part)
 			if err != nil {
 				continue
 			}
-			if nestedBoundary := params["boundary"]; nestedBoundary != "" {
-				if text, err := extractMultipartText(part, nestedBoundary); err == nil && text != "" {
-					return text, nil
-				}
+			text := toUTF8(content, partType)
+			found.parts = append(found.parts, Part{ContentType: mediaType, Text: text})
+
+			// Keep the first text/plain part, HTML as fallback
+			if mediaType == "text/plain" && found.text == "" {
+				found.text = text
+			}
+			if mediaType == "text/html" && found.html == "" {
+				found.html = text
 			}
 		}
 	}
+}
 
-	// If we have HTML content but no text/plain, use it
-	if htmlContent != "" {
-		return htmlContent, nil
+// decodePart reads and decodes a multipart section. quoted-printable parts are
+// already decoded by the multipart reader
+func decodePart(part *multipart.Part) ([]byte, error) {
+	data, err := decodeBody(part, part.Header.Get("Content-Transfer-Encoding"))
+	if err != nil {
+		return nil, fmt.Errorf("decoding part: %w", err)
 	}
 
-	return "", fmt.Errorf("no text/plain or text/html part found")
+	return data, nil
 }
 
-// decodePart reads and decodes a multipart section
-func decodePart(part *multipart.Part) (string, error) {
-	encoding := part.Header.Get("Content-Transfer-Encoding")
-	var reader io.Reader = part
-
-	if strings.EqualFold(encoding, "base64") {
-		reader = base64.NewDecoder(base64.StdEncoding, part)
-	}
+// decodeBody reads a body, undoing its Content-Transfer-Encoding
+func decodeBody(body io.Reader, encoding string) ([]byte, error) {
+	return io.ReadAll(transferDecoder(body, encoding))
+}
 
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return "", fmt.Errorf("decoding part: %w", err)
+// transferDecoder wraps body in a reader undoing its Content-Transfer-Encoding
+func transferDecoder(body io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
 	}
-
-	return string(data), nil
+	return body
 }
 
 // DecodeEmailContent extracts plain text content from raw email data (legacy compatibility)
@@ -0,0 +1,121 @@
+package email
+
+import (
+	"strings"
+	"testing"
+)
+
+const attachedMessage = `Subject: Fw: Deposit Notice
+From: Forwarder <forwarder@example.com>
+Date: Tue, 16 Sep 2025 09:12:03 -0400
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="alt"
+
+--alt
+Content-Type: text/plain; charset=utf-8
+
+see attached
+
+--alt--
+
+--outer
+Content-Type: message/rfc822
+
+Subject: Deposit Notice
+From: Bank <alerts@example.com>
+Date: Mon, 15 Sep 2025 08:18:20 -0600
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: base64
+
+QSBkZXBvc2l0IG9mICQxMC4wMCB3YXMgbWFkZS4=
+
+--outer--
+`
+
+func TestParseAttachedMessage(t *testing.T) {
+	msg, err := Parse([]byte(strings.ReplaceAll(attachedMessage, "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if got := msg.Header.Get("Subject"); got != "Deposit Notice" {
+		t.Errorf("Subject = %q; want %q", got, "Deposit Notice")
+	}
+	if got := strings.TrimSpace(msg.Text); got != "A deposit of $10.00 was made." {
+		t.Errorf("Text = %q; want decoded inner body", got)
+	}
+
+	if msg.Outer == nil || !msg.Attached {
+		t.Fatal("want the wrapping message as Outer with Attached set")
+	}
+	if got := msg.Envelope().Header.Get("From"); got != "Forwarder <forwarder@example.com>" {
+		t.Errorf("envelope From = %q", got)
+	}
+	if got := strings.TrimSpace(msg.Outer.Text); got != "see attached" {
+		t.Errorf("outer Text = %q; want %q", got, "see attached")
+	}
+}
+
+// a broken attached message leaves the wrapper to parse rather than failing the email
+func TestParseBrokenAttachedMessage(t *testing.T) {
+	raw := strings.Replace(attachedMessage, "Subject: Deposit Notice\n", "not a header line\n", 1)
+	msg, err := Parse([]byte(strings.ReplaceAll(raw, "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if msg.Outer != nil || msg.Attached {
+		t.Error("want the wrapping message itself")
+	}
+	if got := strings.TrimSpace(msg.Text); got != "see attached" {
+		t.Errorf("Text = %q; want the wrapper text", got)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].ContentType != "message/rfc822" {
+		t.Errorf("Attachments = %+v; want the attached message", msg.Attachments)
+	}
+}
+
+// only the first attached message is unwrapped, the others are attachments
+func TestParseSeveralAttachedMessages(t *testing.T) {
+	second := "--outer\n" +
+		"Content-Type: message/rfc822\n" +
+		"Content-Disposition: attachment; filename=\"second.eml\"\n" +
+		"\n" +
+		"Subject: Payment Made\n" +
+		"\n" +
+		"A payment was made.\n" +
+		"\n" +
+		"--outer--\n"
+	raw := strings.Replace(attachedMessage, "--outer--\n", second, 1)
+	msg, err := Parse([]byte(strings.ReplaceAll(raw, "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if got := msg.Header.Get("Subject"); got != "Deposit Notice" {
+		t.Errorf("Subject = %q; want the first attached message", got)
+	}
+	atts := msg.Outer.Attachments
+	if len(atts) != 1 || atts[0].ContentType != "message/rfc822" || atts[0].Filename != "second.eml" {
+		t.Fatalf("outer Attachments = %+v; want the second message", atts)
+	}
+	if data, err := atts[0].Content(); err != nil || !strings.Contains(string(data), "Subject: Payment Made") {
+		t.Errorf("Content() = %q, %v", data, err)
+	}
+}
+
+func TestParsePlainMessage(t *testing.T) {
+	msg, err := Parse([]byte("Subject: hi\r\n\r\nbody\r\n"))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if msg.Outer != nil {
+		t.Errorf("Outer = %v; want nil", msg.Outer)
+	}
+	if msg.Envelope() != msg {
+		t.Error("Envelope() of a direct message should be itself")
+	}
+}
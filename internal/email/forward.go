@@ -0,0 +1,200 @@
+package email
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// forwardMarkers match the separator line mail clients put above an inline forward
+var forwardMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`^-+ ?Forwarded [Mm]essage ?-+$`), // proton, gmail, thunderbird
+	regexp.MustCompile(`^-+ ?Original Message ?-+$`),     // outlook (older)
+	regexp.MustCompile(`^_{10,}$`),                       // outlook
+	regexp.MustCompile(`^Begin forwarded message:$`),     // apple mail
+}
+
+// forwardHeaders maps the header names clients use in a forward block to canonical ones
+var forwardHeaders = map[string]string{
+	"from":     "From",
+	"to":       "To",
+	"cc":       "Cc",
+	"reply-to": "Reply-To",
+	"subject":  "Subject",
+	"date":     "Date",
+	"sent":     "Date", // outlook
+}
+
+// unwrapForward looks for an inline forwarded message in the text of m and returns it
+// with m as its Outer. returns nil if m is not an inline forward
+func unwrapForward(m *Message) *Message {
+	text := m.Text
+	html := ""
+	if LooksLikeHTML(text) {
+		// html only, the forward header is in markup and only shows once rendered.
+		// the inner message keeps the html so parsers reading the markup still can
+		text = Normalize(text)
+		html = m.HTML
+	}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	start := -1
+	for i, line := range lines {
+		if isForwardMarker(unquote(line)) {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+
+	rest := make([]string, 0, len(lines)-start)
+	for _, line := range lines[start:] {
+		rest = append(rest, unquote(line))
+	}
+
+	header, body := splitForwardHeader(rest)
+	if header.Get("From") == "" && header.Get("Subject") == "" {
+		return nil
+	}
+
+	floating := false
+	if raw := header.Get("Date"); raw != "" {
+		if t, zoned, ok := parseForwardDate(raw, envelopeLocation(m.Header)); ok {
+			header["Date"] = []string{t.Format(time.RFC1123Z)}
+			floating = !zoned
+		}
+	}
+
+	return &Message{
+		Header:       header,
+		Text:         strings.Join(body, "\n"),
+		HTML:         html,
+		Outer:        m,
+		FloatingDate: floating,
+	}
+}
+
+func isForwardMarker(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, re := range forwardMarkers {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// unquote strips one level of "> " quoting from a line
+func unquote(line string) string {
+	if !strings.HasPrefix(line, ">") {
+		return line
+	}
+	line = strings.TrimPrefix(line, ">")
+	return strings.TrimPrefix(line, " ")
+}
+
+// splitForwardHeader reads the "From: / Date: / Subject:" block at the top of a forward
+// and returns it along with the remaining body lines
+func splitForwardHeader(lines []string) (mail.Header, []string) {
+	header := mail.Header{}
+
+	i := 0
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+
+	last := ""
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+
+		// folded continuation of the previous header
+		if last != "" && (line[0] == ' ' || line[0] == '\t') {
+			vals := header[last]
+			vals[len(vals)-1] += " " + strings.TrimSpace(line)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		canonical, known := forwardHeaders[strings.ToLower(strings.TrimSpace(key))]
+		if !ok || !known {
+			break
+		}
+
+		last = canonical
+		header[canonical] = append(header[canonical], strings.TrimSpace(value))
+	}
+
+	return header, lines[i:]
+}
+
+var (
+	forwardDateNoise = regexp.MustCompile(`^(?:On\s+)?(?:(?:Mon|Tue|Wed|Thu|Fri|Sat|Sun)[a-z]*,?\s+)?`)
+	forwardOrdinal   = regexp.MustCompile(`(\d)(?:st|nd|rd|th)\b`)
+)
+
+// layouts used by mail clients in forward blocks once weekday, "at" and ordinals are removed
+var forwardDateLayouts = []string{
+	"January 2, 2006 3:04 PM",
+	"January 2, 2006 3:04:05 PM",
+	"Jan 2, 2006 3:04 PM",
+	"Jan 2, 2006 3:04:05 PM",
+	"2 January 2006 15:04",
+	"2 Jan 2006 15:04",
+	"January 2, 2006 15:04",
+	"2006-01-02 15:04",
+}
+
+// zone abbreviations (offset in minutes) mail clients print in forward blocks,
+// time.Parse cannot resolve these on its own
+var forwardZones = map[string]int{
+	"UTC": 0, "GMT": 0,
+	"EST": -300, "EDT": -240,
+	"CST": -360, "CDT": -300,
+	"MST": -420, "MDT": -360,
+	"PST": -480, "PDT": -420,
+	"AST": -240, "ADT": -180,
+	"NST": -210, "NDT": -150,
+}
+
+// parseForwardDate parses the many date formats found in forward blocks. dates without
+// a zone are interpreted in loc, zoned reports whether raw had one
+func parseForwardDate(raw string, loc *time.Location) (t time.Time, zoned, ok bool) {
+	if t, err := mail.ParseDate(raw); err == nil {
+		return t, true, true
+	}
+
+	clean := forwardDateNoise.ReplaceAllString(strings.TrimSpace(raw), "")
+	clean = forwardOrdinal.ReplaceAllString(clean, "$1")
+	clean = strings.Replace(clean, " at ", " ", 1)
+	clean = strings.Join(strings.Fields(clean), " ")
+
+	if fields := strings.Fields(clean); len(fields) > 0 {
+		if offset, ok := forwardZones[fields[len(fields)-1]]; ok {
+			loc, zoned = time.FixedZone(fields[len(fields)-1], offset*60), true
+			clean = strings.Join(fields[:len(fields)-1], " ")
+		}
+	}
+
+	for _, layout := range forwardDateLayouts {
+		if t, err := time.ParseInLocation(layout, clean, loc); err == nil {
+			return t, zoned, true
+		}
+	}
+
+	return time.Time{}, false, false
+}
+
+// envelopeLocation returns the zone of the envelope's Date header, which is the best
+// guess we have for zone-less times in a forward block
+func envelopeLocation(h mail.Header) *time.Location {
+	if t, err := h.Date(); err == nil {
+		return t.Location()
+	}
+	return time.UTC
+}
@@ -0,0 +1,187 @@
+package email
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseInlineForward(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		subject string
+		from    string
+		date    time.Time
+		text    string
+
+		floating bool
+	}{
+		{
+			name: "proton",
+			body: "------- Forwarded Message -------\n" +
+				"From: Bank <alerts@example.com>\n" +
+				"Date: On Saturday, September 13th, 2025 at 2:57 PM\n" +
+				"Subject: You made a purchase.\n" +
+				"To: Me <me@example.com>\n" +
+				"\n" +
+				"> a purchase of $39.50 was made\n" +
+				">\n" +
+				"> Thank you!",
+			subject:  "You made a purchase.",
+			from:     "Bank <alerts@example.com>",
+			date:     time.Date(2025, time.September, 13, 14, 57, 0, 0, time.UTC),
+			text:     "a purchase of $39.50 was made\n\nThank you!",
+			floating: true,
+		},
+		{
+			name: "gmail",
+			body: "---------- Forwarded message ---------\n" +
+				"From: Bank <alerts@example.com>\n" +
+				"Date: Sat, Sep 13, 2025 at 2:57 PM\n" +
+				"Subject: You made a purchase.\n" +
+				"To: <me@example.com>\n" +
+				"\n" +
+				"a purchase of $39.50 was made",
+			subject:  "You made a purchase.",
+			from:     "Bank <alerts@example.com>",
+			date:     time.Date(2025, time.September, 13, 14, 57, 0, 0, time.UTC),
+			text:     "a purchase of $39.50 was made",
+			floating: true,
+		},
+		{
+			name: "outlook",
+			body: "Sent from my phone\n" +
+				"\n" +
+				"________________________________\n" +
+				"From: Bank <alerts@example.com>\n" +
+				"Sent: Saturday, September 13, 2025 2:57 PM\n" +
+				"To: Me <me@example.com>\n" +
+				"Subject: You made a purchase.\n" +
+				"\n" +
+				"a purchase of $39.50 was made",
+			subject:  "You made a purchase.",
+			from:     "Bank <alerts@example.com>",
+			date:     time.Date(2025, time.September, 13, 14, 57, 0, 0, time.UTC),
+			text:     "a purchase of $39.50 was made",
+			floating: true,
+		},
+		{
+			name: "apple mail",
+			body: "Begin forwarded message:\n" +
+				"\n" +
+				"> From: Bank <alerts@example.com>\n" +
+				"> Subject: You made a purchase.\n" +
+				"> Date: September 13, 2025 at 2:57:00 PM EDT\n" +
+				"> To: Me <me@example.com>\n" +
+				"> \n" +
+				"> a purchase of $39.50 was made",
+			subject: "You made a purchase.",
+			from:    "Bank <alerts@example.com>",
+			date:    time.Date(2025, time.September, 13, 18, 57, 0, 0, time.UTC),
+			text:    "a purchase of $39.50 was made",
+		},
+		{
+			name: "thunderbird",
+			body: "-------- Forwarded Message --------\n" +
+				"Subject: \tYou made a purchase.\n" +
+				"Date: \tSat, 13 Sep 2025 14:57:00 -0600\n" +
+				"From: \tBank <alerts@example.com>\n" +
+				"To: \tme@example.com\n" +
+				"\n" +
+				"a purchase of $39.50 was made",
+			subject: "You made a purchase.",
+			from:    "Bank <alerts@example.com>",
+			date:    time.Date(2025, time.September, 13, 20, 57, 0, 0, time.UTC),
+			text:    "a purchase of $39.50 was made",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := "Subject: Fw: You made a purchase.\n" +
+				"From: Me <me@example.com>\n" +
+				"Date: Sun, 14 Sep 2025 18:01:59 +0000\n" +
+				"\n" + tt.body
+
+			msg, err := Parse([]byte(raw))
+			if err != nil {
+				t.Fatalf("Parse returned error: %v", err)
+			}
+
+			if msg.Outer == nil {
+				t.Fatal("Outer is nil; forward was not unwrapped")
+			}
+			if got := msg.Outer.Header.Get("Subject"); got != "Fw: You made a purchase." {
+				t.Errorf("outer Subject = %q", got)
+			}
+			if got := msg.Header.Get("Subject"); got != tt.subject {
+				t.Errorf("Subject = %q; want %q", got, tt.subject)
+			}
+			if got := msg.Header.Get("From"); got != tt.from {
+				t.Errorf("From = %q; want %q", got, tt.from)
+			}
+
+			date, err := msg.Header.Date()
+			if err != nil {
+				t.Fatalf("Date header %q not parseable: %v", msg.Header.Get("Date"), err)
+			}
+			if !date.Equal(tt.date) {
+				t.Errorf("Date = %v; want %v", date, tt.date)
+			}
+			if msg.FloatingDate != tt.floating {
+				t.Errorf("FloatingDate = %v; want %v", msg.FloatingDate, tt.floating)
+			}
+
+			if got := strings.TrimSpace(msg.Text); got != tt.text {
+				t.Errorf("Text = %q; want %q", got, tt.text)
+			}
+		})
+	}
+}
+
+func TestParseNotAForward(t *testing.T) {
+	raw := "Subject: hello\n\n________________________________\nnot a header block\n"
+
+	msg, err := Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if msg.Outer != nil {
+		t.Errorf("Outer = %v; want nil for a message without a forward block", msg.Outer)
+	}
+}
+
+func TestParseHTMLForward(t *testing.T) {
+	raw := "Subject: Fwd: You made a purchase.\n" +
+		"From: Me <me@example.com>\n" +
+		"Date: Sun, 14 Sep 2025 18:01:59 +0000\n" +
+		"Content-Type: text/html; charset=utf-8\n" +
+		"\n" +
+		`<div dir="ltr"><div class="gmail_quote">---------- Forwarded message ---------<br>` +
+		`From: <strong>Bank</strong> &lt;<a href="mailto:alerts@example.com">alerts@example.com</a>&gt;<br>` +
+		`Date: Sat, Sep 13, 2025 at 2:57&#8239;PM<br>` +
+		`Subject: You made a purchase.<br>` +
+		`To: &lt;me@example.com&gt;<br></div><br><br>` +
+		`<p>a purchase of <b>$39.50</b> was made</p></div>`
+
+	msg, err := Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if msg.Outer == nil {
+		t.Fatal("Outer is nil; forward was not unwrapped")
+	}
+	if got := msg.Header.Get("Subject"); got != "You made a purchase." {
+		t.Errorf("Subject = %q", got)
+	}
+	if got := msg.Header.Get("From"); got != "Bank <alerts@example.com>" {
+		t.Errorf("From = %q", got)
+	}
+	if got := strings.TrimSpace(msg.Text); got != "a purchase of $39.50 was made" {
+		t.Errorf("Text = %q", got)
+	}
+	if msg.HTML == "" {
+		t.Error("HTML was dropped from the unwrapped message")
+	}
+}
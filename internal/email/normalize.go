@@ -0,0 +1,175 @@
+package email
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Normalize turns a decoded body into text parsers can match against reliably:
+// html is rendered to text, invisible characters are removed and whitespace and
+// dashes are unified
+func Normalize(text string) string {
+	if LooksLikeHTML(text) {
+		text = HTMLToText(text)
+	} else if htmlEntity.MatchString(text) {
+		// text parts rendered from html by the sender sometimes keep the entities
+		text = html.UnescapeString(text)
+	}
+
+	return cleanText(text)
+}
+
+var (
+	htmlMarker = regexp.MustCompile(`(?i)<(?:!doctype\s+html|html|head|body|table|div|p|br)[\s/>]`)
+	htmlEntity = regexp.MustCompile(`&(?:[a-zA-Z]+|#\d+|#x[0-9a-fA-F]+);`)
+	htmlSpace  = regexp.MustCompile(`[ \t\r\n\f]+`)
+)
+
+// LooksLikeHTML reports whether a body is html markup rather than plain text
+func LooksLikeHTML(text string) bool {
+	return htmlMarker.MatchString(text)
+}
+
+// HTMLToText renders html as readable plain text: block elements and table rows
+// become lines, cells are separated by spaces, links and images keep their text
+func HTMLToText(src string) string {
+	doc, err := xhtml.Parse(strings.NewReader(src))
+	if err != nil {
+		return src
+	}
+
+	var b strings.Builder
+	renderNode(&b, doc)
+
+	return b.String()
+}
+
+// NodeText renders a parsed html node and its children as normalized text
+func NodeText(n *xhtml.Node) string {
+	var b strings.Builder
+	renderNode(&b, n)
+
+	return cleanText(b.String())
+}
+
+// skipped elements never contain text a reader would see
+var skippedElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Title:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+}
+
+// block elements start and end a line
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Center: true, atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true,
+	atom.Fieldset: true, atom.Footer: true, atom.Form: true, atom.H1: true, atom.H2: true,
+	atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true,
+	atom.Hr: true, atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true,
+	atom.P: true, atom.Pre: true, atom.Section: true, atom.Table: true, atom.Tr: true,
+	atom.Ul: true,
+}
+
+func renderNode(b *strings.Builder, n *xhtml.Node) {
+	switch n.Type {
+	case xhtml.TextNode:
+		// source line breaks are not rendered, only elements break lines
+		b.WriteString(htmlSpace.ReplaceAllString(n.Data, " "))
+		return
+	case xhtml.CommentNode:
+		return
+	case xhtml.ElementNode:
+		if skippedElements[n.DataAtom] {
+			return
+		}
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		b.WriteString("\n")
+		return
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			b.WriteString(" " + alt + " ")
+		}
+		return
+	}
+
+	block := n.Type == xhtml.ElementNode && blockElements[n.DataAtom]
+	if block && n.DataAtom != atom.Tr { // rows only end a line, so a table has no blank lines
+		b.WriteString("\n")
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		renderNode(b, c)
+	}
+
+	switch {
+	case block:
+		b.WriteString("\n")
+	case n.DataAtom == atom.Td || n.DataAtom == atom.Th:
+		b.WriteString(" ")
+	}
+}
+
+func attr(n *xhtml.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// invisible characters used by bank templates for preheader padding and layout
+var invisibleReplacer = strings.NewReplacer(
+	"\u00ad", "", // soft hyphen
+	"\u034f", "", // combining grapheme joiner
+	"\u180e", "", // mongolian vowel separator
+	"\u200b", "", // zero width space
+	"\u200c", "", // zero width non-joiner
+	"\u200d", "", // zero width joiner
+	"\u2060", "", // word joiner
+	"\ufeff", "", // zero width no-break space
+	"\u00a0", " ", // no-break space
+	"\u2007", " ", // figure space
+	"\u2009", " ", // thin space
+	"\u2002", " ", // en space
+	"\u2003", " ", // em space
+	"\u202f", " ", // narrow no-break space
+	"\t", " ",
+	"\r", "",
+	"\u2010", "-", // hyphen
+	"\u2011", "-", // non-breaking hyphen
+	"\u2012", "-", // figure dash
+	"\u2013", "-", // en dash
+	"\u2014", "-", // em dash
+	"\u2015", "-", // horizontal bar
+	"\u2212", "-", // minus sign
+)
+
+var (
+	spaceRun = regexp.MustCompile(` {2,}`)
+	blankRun = regexp.MustCompile(`\n{3,}`)
+)
+
+// cleanText removes invisible characters, unifies dashes and collapses whitespace,
+// keeping at most one blank line between paragraphs
+func cleanText(text string) string {
+	text = invisibleReplacer.Replace(text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spaceRun.ReplaceAllString(line, " "))
+	}
+	text = strings.Join(lines, "\n")
+
+	return strings.TrimSpace(blankRun.ReplaceAllString(text, "\n\n"))
+}
@@ -0,0 +1,40 @@
+package email
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "html table and links",
+			in: `<!DOCTYPE html><html><head><title>alert</title><style>td{color:red}</style></head><body>
+<p>A purchase of <b>$1,234.56</b>
+   was made.</p>
+<table><tr><td>Purchase Amount:</td><td>$1,234.56</td></tr>
+<tr><td>Merchant:</td><td><a href="https://example.com">TIM&nbsp;HORTONS</a></td></tr></table>
+<img src="logo.png" alt="Some Bank"><br>Call 1&#8209;800&#8209;555&#8209;0100</body></html>`,
+			want: "A purchase of $1,234.56 was made.\n\nPurchase Amount: $1,234.56\nMerchant: TIM HORTONS\n\nSome Bank\nCall 1-800-555-0100",
+		},
+		{
+			name: "plain text with entities and invisibles",
+			in:   "&#847; &zwnj;   &#8199;\n\n\n\n we&rsquo;re   letting you know — thanks \n",
+			want: "we’re letting you know - thanks",
+		},
+		{
+			name: "preheader padding",
+			in:   "> ͏   ­ ͏   ­\n>\n> Hello,\r\n",
+			want: ">\n>\n> Hello,",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+)
+
+// Part is a text/plain or text/html body part, decoded to UTF-8
+type Part struct {
+	ContentType string // media type, e.g. "text/plain"
+	Text        string
+}
+
+// Attachment describes a non-text part. the part is kept as transferred and only
+// decoded by Open or Content, so attachments no parser reads cost no decoding
+type Attachment struct {
+	Filename    string
+	ContentType string // media type, e.g. "application/pdf"
+	ContentID   string // for inline images referenced from html, without the <>
+	Inline      bool   // Content-Disposition: inline
+	Size        int    // size of the part as transferred, before decoding
+
+	raw      []byte
+	encoding string
+}
+
+// Open returns a reader decoding the attachment content as it is read
+func (a Attachment) Open() io.Reader {
+	return transferDecoder(bytes.NewReader(a.raw), a.encoding)
+}
+
+// Content returns the decoded attachment content
+func (a Attachment) Content() ([]byte, error) {
+	data, err := io.ReadAll(a.Open())
+	if err != nil {
+		return nil, fmt.Errorf("decoding attachment %q: %w", a.Filename, err)
+	}
+	return data, nil
+}
+
+// newAttachment reads a part as an attachment without decoding it
+func newAttachment(part *multipart.Part, mediaType string, typeParams map[string]string) (Attachment, error) {
+	raw, err := io.ReadAll(part)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("reading attachment: %w", err)
+	}
+
+	disposition, dispParams, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+
+	filename := dispParams["filename"]
+	if filename == "" {
+		filename = typeParams["name"]
+	}
+	if decoded, err := wordDecoder.DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+
+	return Attachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		ContentID:   strings.Trim(part.Header.Get("Content-Id"), "<> "),
+		Inline:      strings.EqualFold(disposition, "inline"),
+		Size:        len(raw),
+		raw:         raw,
+		// quoted-printable parts are decoded by the multipart reader, which drops the header
+		encoding: part.Header.Get("Content-Transfer-Encoding"),
+	}, nil
+}
+
+// isAttachment reports whether a part is an attachment rather than body text
+func isAttachment(part *multipart.Part, mediaType string) bool {
+	disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition"))
+	if strings.EqualFold(disposition, "attachment") {
+		return true
+	}
+
+	return mediaType != "text/plain" && mediaType != "text/html"
+}
@@ -0,0 +1,96 @@
+package email
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+const withAttachments = `Subject: Your statement
+From: Bank <alerts@example.com>
+Content-Type: multipart/mixed; boundary="mixed"
+
+--mixed
+Content-Type: multipart/related; boundary="related"
+
+--related
+Content-Type: multipart/alternative; boundary="alt"
+
+--alt
+Content-Type: text/plain; charset=utf-8
+
+Your statement is attached.
+--alt
+Content-Type: text/html; charset=utf-8
+
+<p>Your statement is attached.</p><img src="cid:logo@bank">
+--alt--
+--related
+Content-Type: image/png
+Content-Transfer-Encoding: base64
+Content-Disposition: inline
+Content-ID: <logo@bank>
+
+iVBORw0KGgo=
+--related--
+--mixed
+Content-Type: application/pdf; name="statement.pdf"
+Content-Transfer-Encoding: base64
+Content-Disposition: attachment; filename="=?UTF-8?Q?relev=C3=A9.pdf?="
+
+JVBERi0xLjQK
+--mixed
+Content-Type: text/csv
+Content-Disposition: attachment; filename="transactions.csv"
+
+date,amount
+2025-09-13,39.50
+--mixed--
+`
+
+func TestParsePartsAndAttachments(t *testing.T) {
+	msg, err := Parse([]byte(strings.ReplaceAll(withAttachments, "\n", "\r\n")))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if len(msg.Parts) != 2 || msg.Parts[0].ContentType != "text/plain" || msg.Parts[1].ContentType != "text/html" {
+		t.Fatalf("Parts = %+v; want text/plain and text/html", msg.Parts)
+	}
+	if strings.TrimSpace(msg.Text) != "Your statement is attached." {
+		t.Errorf("Text = %q", msg.Text)
+	}
+
+	if len(msg.Attachments) != 3 {
+		t.Fatalf("got %d attachments; want 3", len(msg.Attachments))
+	}
+
+	logo := msg.Attachments[0]
+	if logo.ContentType != "image/png" || !logo.Inline || logo.ContentID != "logo@bank" {
+		t.Errorf("inline image = %+v", logo)
+	}
+
+	pdf := msg.Attachments[1]
+	if pdf.Filename != "relevé.pdf" || pdf.ContentType != "application/pdf" || pdf.Inline {
+		t.Errorf("pdf = %+v", pdf)
+	}
+	content, err := pdf.Content()
+	if err != nil {
+		t.Fatalf("Content() returned error: %v", err)
+	}
+	if string(content) != "%PDF-1.4\n" {
+		t.Errorf("pdf Content() = %q", content)
+	}
+	if streamed, err := io.ReadAll(pdf.Open()); err != nil || string(streamed) != string(content) {
+		t.Errorf("pdf Open() read %q, %v; want what Content() returns", streamed, err)
+	}
+
+	// text parts with an attachment disposition are not body text
+	csv := msg.Attachments[2]
+	if csv.Filename != "transactions.csv" {
+		t.Errorf("csv = %+v", csv)
+	}
+	if content, _ := csv.Content(); !strings.Contains(string(content), "2025-09-13,39.50") {
+		t.Errorf("csv Content() = %q", content)
+	}
+}
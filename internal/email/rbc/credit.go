@@ -20,9 +20,10 @@ func (p *credit) Match(m parser.EmailMeta) bool {
 func (p *credit) Parse(m parser.EmailMeta) (*domain.Transaction, error) {
 	patterns := map[string]*regexp.Regexp{
 		"account": regexp.MustCompile(`(\*{12}\d+|\*+\d+)`),
-		"amount":  regexp.MustCompile(`\$(\d+\.\d{2})`),
-		"txdate":  regexp.MustCompile(`([A-Za-z]+ \d{1,2}, \d{4})`),
+		"amount":  regexp.MustCompile(`(` + parser.MoneyPattern + `)`),
+		"txdate":  regexp.MustCompile(`(` + parser.DatePattern + `)`),
 		"desc":    regexp.MustCompile(`from ([A-Z][A-Z' ]+[A-Z])`),
+		"txtime":  txtimePattern,
 	}
 	fields, err := parser.ExtractFields(m.Text, patterns)
 	if err != nil {
@@ -35,6 +36,6 @@ func (p *credit) Parse(m parser.EmailMeta) (*domain.Transaction, error) {
 		"rbc",
 		"CAD",
 		domain.In,
-		strings.TrimSpace(fields["desc"]),
+		strings.TrimSpace(fields.Get("desc")),
 	)
 }
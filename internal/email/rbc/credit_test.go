@@ -34,7 +34,7 @@ func TestCreditParserForwarded(t *testing.T) {
 		expectedTransactionDetails{
 			Account:     "************1001",
 			Amount:      "840.72",
-			Date:        time.Date(2025, time.June, 10, 0, 0, 0, 0, time.UTC),
+			Date:        time.Date(2025, time.June, 10, 19, 42, 0, 0, toronto),
 			Currency:    "CAD",
 			Direction:   domain.In,
 			Description: "SOME MERCHANT",
@@ -20,8 +20,8 @@ func (d *deposit) Match(m parser.EmailMeta) bool {
 func (d *deposit) Parse(m parser.EmailMeta) (*domain.Transaction, error) {
 	patterns := map[string]*regexp.Regexp{
 		"account": regexp.MustCompile(`bank account ([A-Za-z]+)`),
-		"amount":  regexp.MustCompile(`\$([0-9,]+\.\d{2})`),
-		"txdate":  regexp.MustCompile(`([A-Za-z]+ \d{1,2}, \d{4})`),
+		"amount":  regexp.MustCompile(`(` + parser.MoneyPattern + `)`),
+		"txdate":  regexp.MustCompile(`(` + parser.DatePattern + `)`),
 	}
 	fields, err := parser.ExtractFields(m.Text, patterns)
 	if err != nil {
@@ -34,7 +34,7 @@ func TestDepositParserForwarded(t *testing.T) {
 		expectedTransactionDetails{
 			Account:     "Savings",
 			Amount:      "1183.98",
-			Date:        time.Date(2025, time.August, 28, 0, 0, 0, 0, time.UTC),
+			Date:        time.Date(2025, time.August, 28, 3, 3, 0, 0, toronto),
 			Currency:    "CAD",
 			Direction:   domain.In,
 			Description: "RBC Deposit",
@@ -0,0 +1,12 @@
+package rbc_test
+
+import (
+	"testing"
+
+	_ "null-email-parser/internal/email/all"
+	"null-email-parser/internal/parser/parsertest"
+)
+
+func TestGolden(t *testing.T) {
+	parsertest.Run(t, "testdata")
+}
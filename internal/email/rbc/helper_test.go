@@ -1,16 +1,17 @@
 package rbc
 
 import (
-	"fmt"
-	"os"
 	"testing"
 	"time"
 
 	"null-email-parser/internal/domain"
-	"null-email-parser/internal/email"
 	"null-email-parser/internal/parser"
+	"null-email-parser/internal/parser/parsertest"
 )
 
+// toronto is the zone rbc dates without one are read in
+var toronto, _ = time.LoadLocation("America/Toronto")
+
 type expectedTransactionDetails struct {
 	Account     string
 	Amount      string
@@ -29,20 +30,8 @@ func assertTransaction(
 ) {
 	t.Helper()
 
-	rawBytes, err := os.ReadFile(fixturePath)
-	if err != nil {
-		t.Fatalf("failed to read fixture %s: %v", fixturePath, err)
-	}
-
-	msg, content, err := email.ParseMessage(rawBytes)
-	if err != nil {
-		t.Fatalf("failed to parse email message from %s: %v", fixturePath, err)
-	}
-
-	meta, err := parser.ToEmailMeta(emailID, msg, content)
-	if err != nil {
-		t.Fatalf("toEmailMeta failed for %s: %v", fixturePath, err)
-	}
+	meta := parsertest.LoadMeta(t, fixturePath)
+	meta.ID = emailID
 
 	if !p.Match(meta) {
 		t.Fatalf("Match(meta) was false for %s; subject=%q", fixturePath, meta.Subject)
@@ -59,8 +48,8 @@ func assertTransaction(
 	if tx.TxAccount != expected.Account {
 		t.Errorf("Account = %q; want %q (fixture: %s)", tx.TxAccount, expected.Account, fixturePath)
 	}
-	if fmt.Sprintf("%.2f", tx.TxAmount) != expected.Amount {
-		t.Errorf("Amount = %q; want %q (fixture: %s)", fmt.Sprintf("%.2f", tx.TxAmount), expected.Amount, fixturePath)
+	if tx.TxAmount.String() != expected.Amount {
+		t.Errorf("Amount = %q; want %q (fixture: %s)", tx.TxAmount, expected.Amount, fixturePath)
 	}
 	if !tx.TxDate.Equal(expected.Date) {
 		t.Errorf("TxnDate = %v; want %v (fixture: %s)", tx.TxDate, expected.Date, fixturePath)
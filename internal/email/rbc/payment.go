@@ -20,8 +20,8 @@ func (p *payment) Match(m parser.EmailMeta) bool {
 func (p *payment) Parse(m parser.EmailMeta) (*domain.Transaction, error) {
 	patterns := map[string]*regexp.Regexp{
 		"account": regexp.MustCompile(`(\*{12}\d+|\*+\d+)`),
-		"amount":  regexp.MustCompile(`\$(\d+\.\d{2})`),
-		"txdate":  regexp.MustCompile(`([A-Za-z]+ \d{1,2}, \d{4})`),
+		"amount":  regexp.MustCompile(`(` + parser.MoneyPattern + `)`),
+		"txdate":  regexp.MustCompile(`(` + parser.DatePattern + `)`),
 	}
 	fields, err := parser.ExtractFields(m.Text, patterns)
 	if err != nil {
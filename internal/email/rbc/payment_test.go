@@ -17,7 +17,7 @@ func TestPaymentParser(t *testing.T) {
 		expectedTransactionDetails{
 			Account:     "************1001",
 			Amount:      "415.54",
-			Date:        time.Date(2025, time.September, 12, 0, 0, 0, 0, time.UTC),
+			Date:        time.Date(2025, time.September, 12, 0, 0, 0, 0, toronto),
 			Currency:    "CAD",
 			Direction:   domain.In,
 			Description: "RBC Payment",
@@ -34,7 +34,7 @@ func TestPaymentParserForwarded(t *testing.T) {
 		expectedTransactionDetails{
 			Account:     "************1001",
 			Amount:      "500.00",
-			Date:        time.Date(2025, time.September, 5, 0, 0, 0, 0, time.UTC),
+			Date:        time.Date(2025, time.September, 5, 23, 54, 0, 0, toronto),
 			Currency:    "CAD",
 			Direction:   domain.In,
 			Description: "RBC Payment",
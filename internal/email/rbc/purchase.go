@@ -12,6 +12,10 @@ func init() { parser.Register(&purchase{}) }
 
 type purchase struct{}
 
+// purchases abroad give the amount in the foreign currency, then what the card was
+// charged: "USD 12.00 ... converted to CAD 16.52 at an exchange rate of 1.3767"
+var convertedPattern = regexp.MustCompile(`converted to (` + parser.MoneyPattern + `)`)
+
 func (p *purchase) Match(m parser.EmailMeta) bool {
 	return strings.Contains(m.Subject, "You made a purchase") &&
 		strings.Contains(m.Text, "RBC Royal Bank")
@@ -20,14 +24,21 @@ func (p *purchase) Match(m parser.EmailMeta) bool {
 func (p *purchase) Parse(m parser.EmailMeta) (*domain.Transaction, error) {
 	patterns := map[string]*regexp.Regexp{
 		"account": regexp.MustCompile(`(\*{12}\d+|\*+\d+)`),
-		"amount":  regexp.MustCompile(`\$(\d+\.\d{2})`),
-		"txdate":  regexp.MustCompile(`([A-Za-z]+ \d{1,2}, \d{4})`),
+		"amount":  regexp.MustCompile(`(` + parser.MoneyPattern + `)`),
+		"txdate":  regexp.MustCompile(`(` + parser.DatePattern + `)`),
 		"desc":    regexp.MustCompile(`towards ([^.]+)\.`),
+		"txtime":  txtimePattern,
+
+		"exchange_rate": regexp.MustCompile(`exchange rate of (\d+(?:[.,]\d+)?)`),
 	}
 	fields, err := parser.ExtractFields(m.Text, patterns)
 	if err != nil {
 		return nil, err
 	}
+	if c := convertedPattern.FindStringSubmatchIndex(m.Text); c != nil {
+		fields["foreign_amount"] = fields["amount"]
+		fields["amount"] = parser.Field{Value: m.Text[c[2]:c[3]], Pattern: convertedPattern.String(), Offset: c[2]}
+	}
 
 	return parser.BuildTransaction(
 		m,
@@ -35,6 +46,6 @@ func (p *purchase) Parse(m parser.EmailMeta) (*domain.Transaction, error) {
 		"rbc",
 		"CAD",
 		domain.Out,
-		strings.TrimSpace(fields["desc"]),
+		strings.TrimSpace(fields.Get("desc")),
 	)
 }
@@ -34,7 +34,7 @@ func TestPurchaseParserForwarded(t *testing.T) {
 		expectedTransactionDetails{
 			Account:     "************1001",
 			Amount:      "39.50",
-			Date:        time.Date(2025, time.September, 13, 0, 0, 0, 0, time.UTC),
+			Date:        time.Date(2025, time.September, 13, 14, 57, 0, 0, toronto),
 			Currency:    "CAD",
 			Direction:   domain.Out,
 			Description: "SOME NO FRILLS 0000",
@@ -68,10 +68,27 @@ func TestPurchaseParserNoAccountForwarded(t *testing.T) {
 		expectedTransactionDetails{
 			Account:     "",
 			Amount:      "90.39",
-			Date:        time.Date(2025, time.September, 3, 0, 0, 0, 0, time.UTC),
+			Date:        time.Date(2025, time.September, 3, 18, 56, 0, 0, toronto),
 			Currency:    "CAD",
 			Direction:   domain.Out,
 			Description: "AMZN Mktp CA",
 		},
 	)
 }
+
+func TestPurchaseParserForwardedAsAttachment(t *testing.T) {
+	assertTransaction(
+		t,
+		&purchase{},
+		filepath.Join("testdata", "att-you-made-a-purchase.decoded.eml"),
+		"test-purchase-att-id",
+		expectedTransactionDetails{
+			Account:     "************1001",
+			Amount:      "1.77",
+			Date:        time.Date(2025, time.September, 15, 8, 18, 20, 0, time.FixedZone("", -6*60*60)),
+			Currency:    "CAD",
+			Direction:   domain.Out,
+			Description: "TIM HORTONS #0000",
+		},
+	)
+}
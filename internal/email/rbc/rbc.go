@@ -0,0 +1,14 @@
+package rbc
+
+import (
+	"regexp"
+
+	"null-email-parser/internal/parser"
+)
+
+// rbc writes dates in eastern time, the zone of its head office
+func init() { parser.RegisterTimezone("rbc", "America/Toronto") }
+
+// txtimePattern finds the time some alerts give after the date of the transaction,
+// "on September 13, 2025 at 2:57 PM". without it the time the email came is used
+var txtimePattern = regexp.MustCompile(`(?:` + parser.DatePattern + `),? at (` + parser.ClockPattern + `)`)
@@ -0,0 +1,24 @@
+package rbc
+
+import (
+	"path/filepath"
+	"testing"
+
+	"null-email-parser/internal/parser"
+	"null-email-parser/internal/parser/parsertest"
+)
+
+// every fixture must be claimed by at most one rbc parser
+func TestFixturesAreUnambiguous(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		meta := parsertest.LoadMeta(t, path)
+		if _, err := parser.Lookup(meta); err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+}
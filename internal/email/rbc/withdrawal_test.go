@@ -34,7 +34,7 @@ func TestWithdrawalParserForwarded(t *testing.T) {
 		expectedTransactionDetails{
 			Account:     "Daily",
 			Amount:      "11.95",
-			Date:        time.Date(2025, time.July, 15, 0, 0, 0, 0, time.UTC),
+			Date:        time.Date(2025, time.July, 15, 3, 19, 0, 0, toronto),
 			Currency:    "CAD",
 			Direction:   domain.Out,
 			Description: "RBC Withdrawal",
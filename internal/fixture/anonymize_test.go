@@ -0,0 +1,95 @@
+package fixture
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnonymize(t *testing.T) {
+	text := "Your card ************4821 was credited for $840.72 from JOHN DOE.\n" +
+		"Hello Jane Smith,\n" +
+		"Call 416-555-2368 or 1-800-769-2512. Confirmation number: AB12CD34\n" +
+		"Account 5551234, 100 King Street West, Toronto, ON, M5H 1A1\n" +
+		"Sent by ROYAL BANK OF CANADA, color #E0E0E0\n"
+
+	got := Default.Anonymize(text)
+	for _, leak := range []string{"4821", "JOHN DOE", "Jane Smith", "416-555-2368", "AB12CD34", "5551234", "100 King Street", "M5H 1A1"} {
+		if strings.Contains(got, leak) {
+			t.Errorf("anonymized text still contains %q:\n%s", leak, got)
+		}
+	}
+	for _, keep := range []string{"************10", "$840.72", "1-800-769-2512", "ROYAL BANK OF CANADA", "#E0E0E0", "Example Street", "Hello "} {
+		if !strings.Contains(got, keep) {
+			t.Errorf("anonymized text lost %q:\n%s", keep, got)
+		}
+	}
+
+	if again := Default.Anonymize(got); again != got {
+		t.Errorf("anonymizing twice changed the text:\n%s\n%s", got, again)
+	}
+	if findings := Default.Find(got); len(findings) > 0 {
+		t.Errorf("Find after Anonymize = %+v; want none", findings)
+	}
+}
+
+// placeholders, merchants, fake links and the addresses banks print are not anyone's data
+func TestAnonymizeKeeps(t *testing.T) {
+	for _, text := range []string{
+		"was credited for $840.72 on June 10, 2025 from SOME MERCHANT.",
+		"a purchase from CORNER COFFEE CO was made",
+		"Royal Bank of Canada RBC WaterPark Place, 88 Queens Quay West, 12th Floor, Toronto, ON, M5J 0B8, Canada",
+		`<a href="https://example.com">View in a browser</a>`,
+	} {
+		if got := Default.Anonymize(text); got != text {
+			t.Errorf("Anonymize(%q) = %q; want it unchanged", text, got)
+		}
+		if findings := Default.Find(text); len(findings) > 0 {
+			t.Errorf("Find(%q) = %+v; want none", text, findings)
+		}
+	}
+}
+
+func TestAnonymizeDeterministic(t *testing.T) {
+	a := Default.Anonymize("from JOHN DOE, card ************4821")
+	b := Default.Anonymize("card ************4821 paid from JOHN DOE")
+	fakeName := strings.TrimPrefix(strings.Split(a, ",")[0], "from ")
+	fakeCard := a[strings.Index(a, "*"):]
+	if !strings.Contains(b, fakeName) || !strings.Contains(b, fakeCard) {
+		t.Errorf("fakes differ between texts: %q, %q", a, b)
+	}
+
+	salted, err := NewAnonymizer(DefaultDetectors, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if salted.Anonymize("card ************4821") == Default.Anonymize("card ************4821") {
+		t.Error("a different salt gave the same fake")
+	}
+}
+
+func TestFind(t *testing.T) {
+	findings := Default.Find("line one\nPaid to MARY MAJOR on card ************4821\n")
+	if len(findings) != 2 {
+		t.Fatalf("findings = %+v; want a name and an account", findings)
+	}
+	for _, f := range findings {
+		if f.Line != 2 {
+			t.Errorf("finding %+v on line %d; want 2", f, f.Line)
+		}
+	}
+}
+
+func TestNewAnonymizer(t *testing.T) {
+	if _, err := NewAnonymizer([]string{"account", "nope"}, DefaultSalt); err == nil {
+		t.Error("unknown detector accepted")
+	}
+
+	a, err := NewAnonymizer([]string{"amount"}, DefaultSalt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := a.Anonymize("a purchase of $1,234.56 from JOHN DOE")
+	if strings.Contains(got, "1,234.56") || !strings.Contains(got, "JOHN DOE") {
+		t.Errorf("amount only anonymizer gave %q", got)
+	}
+}
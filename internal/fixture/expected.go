@@ -0,0 +1,84 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// ExpectedSuffix of the golden file next to every fixture
+const ExpectedSuffix = ".expected.json"
+
+// Expected is the content of a golden file
+type Expected struct {
+	Parser       string        `json:"parser"`          // id of the matching parser, "" if none
+	Error        string        `json:"error,omitempty"` // parse error, if any
+	Transactions []Transaction `json:"transactions"`
+}
+
+// Transaction holds the fields of a domain.Transaction a parser sets
+type Transaction struct {
+	Date            string  `json:"date"` // RFC3339
+	Bank            string  `json:"bank"`
+	Account         string  `json:"account"`
+	AccountKind     string  `json:"account_kind,omitempty"`
+	Amount          string  `json:"amount"`
+	Currency        string  `json:"currency"`
+	Direction       string  `json:"direction"`
+	Description     string  `json:"description"`
+	Category        string  `json:"category,omitempty"`
+	Merchant        string  `json:"merchant,omitempty"`
+	ForeignAmount   *string `json:"foreign_amount,omitempty"`
+	ForeignCurrency *string `json:"foreign_currency,omitempty"`
+	ExchangeRate    *string `json:"exchange_rate,omitempty"`
+}
+
+// GoldenPath returns the path of the golden file of a fixture
+func GoldenPath(fixture string) string {
+	return strings.TrimSuffix(fixture, Suffix) + ExpectedSuffix
+}
+
+// WriteExpected writes the golden file of a fixture
+func WriteExpected(fixture string, e Expected) error {
+	data, err := MarshalExpected(e)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GoldenPath(fixture), data, 0644)
+}
+
+// MarshalExpected encodes a golden file the way WriteExpected writes it
+func MarshalExpected(e Expected) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Placeholder is a golden file of TODOs, it fails until it is filled in. parser is
+// the id of the parser expected to match, "" if not known yet
+func Placeholder(parser, bank string) Expected {
+	if parser == "" {
+		parser = "TODO"
+	}
+	if bank == "" {
+		bank = "TODO"
+	}
+	return Expected{
+		Parser: parser,
+		Transactions: []Transaction{{
+			Date:        "TODO",
+			Bank:        bank,
+			Account:     "TODO",
+			Amount:      "TODO",
+			Currency:    "TODO",
+			Direction:   "TODO",
+			Description: "TODO",
+		}},
+	}
+}
@@ -0,0 +1,163 @@
+// Package fixture turns received emails into anonymized test fixtures, the
+// testdata/*.decoded.eml files parsers are tested against
+package fixture
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"null-email-parser/internal/email"
+)
+
+// Suffix of decoded fixture files
+const Suffix = ".decoded.eml"
+
+// Fixture is a decoded, anonymized email
+type Fixture struct {
+	Name    string // file name without Suffix, from the anonymized subject as received
+	Subject string // anonymized subject of the notification, forwards unwrapped
+	From    string // sender of the notification, not anonymized
+	Data    []byte // content of the fixture file
+}
+
+// Decode extracts the text of a raw email and anonymizes it with the default detectors
+func Decode(data []byte) (*Fixture, error) {
+	return Default.Decode(data)
+}
+
+// Decode extracts the text of a raw email and anonymizes it
+func (a *Anonymizer) Decode(data []byte) (*Fixture, error) {
+	parsed, err := email.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email - cannot extract subject: %w", err)
+	}
+
+	// keep inline forwards as they were received, tests exercise the unwrapping too.
+	// attached messages are written out on their own
+	msg := parsed
+	for msg.Outer != nil && !msg.Attached {
+		msg = msg.Outer
+	}
+
+	// subjects name payers and payees too, e.g. "JOHN DOE sent you money"
+	subject := a.Anonymize(msg.Header.Get("Subject"))
+	if subject == "" {
+		subject = "no-subject"
+	}
+	inner := subject
+	if parsed != msg {
+		inner = a.Anonymize(parsed.Header.Get("Subject"))
+	}
+
+	return &Fixture{
+		Name:    Slug(subject),
+		Subject: inner,
+		From:    parsed.Header.Get("From"),
+		Data: fmt.Appendf(nil, `Subject: %s
+From: %s
+To: %s
+Date: %s
+
+%s`,
+			subject,
+			AnonymizeAddress(msg.Header.Get("From")),
+			AnonymizeAddress(msg.Header.Get("To")),
+			msg.Header.Get("Date"),
+			a.Anonymize(AnonymizeContent(msg.Text))),
+	}, nil
+}
+
+// Write saves the fixture in dir and returns its path. the name is numbered when a
+// fixture of the same name exists
+func (f *Fixture) Write(dir string) (string, error) {
+	path := uniquePath(dir, f.Name)
+	if err := os.WriteFile(path, f.Data, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// uniquePath returns dir/name.decoded.eml, numbered if it exists already
+func uniquePath(dir, name string) string {
+	path := filepath.Join(dir, name+Suffix)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+
+	for counter := 1; ; counter++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, counter, Suffix))
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+	}
+}
+
+// Slug turns a subject into a file name, e.g. "You made a purchase." becomes
+// "you-made-a-purchase"
+func Slug(subject string) string {
+	clean := strings.ToLower(subject)
+	clean = strings.ReplaceAll(clean, " ", "-")
+
+	var result strings.Builder
+	for _, r := range clean {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			result.WriteRune(r)
+		}
+	}
+
+	clean = result.String()
+
+	for strings.Contains(clean, "--") {
+		clean = strings.ReplaceAll(clean, "--", "-")
+	}
+	clean = strings.Trim(clean, "-")
+
+	if clean == "" {
+		clean = "no-subject"
+	}
+
+	return clean
+}
+
+var (
+	nameEmailPattern = regexp.MustCompile(`^(.+?)\s*<([^>]+)>$`)
+	emailOnlyPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+	emailPattern     = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+)
+
+// AnonymizeAddress replaces the addresses of a header with example ones
+func AnonymizeAddress(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	if nameEmailPattern.MatchString(header) {
+		return "Example <email@example.com>"
+	}
+
+	if emailOnlyPattern.MatchString(strings.TrimSpace(header)) {
+		return "email@example.com"
+	}
+
+	return emailPattern.ReplaceAllString(header, "email@example.com")
+}
+
+var (
+	httpPattern     = regexp.MustCompile(`https?://[^\s\)>\]]+`)
+	fromPattern     = regexp.MustCompile(`From:\s*(.+?)\s*<([^>]+)>`)
+	toQuotedPattern = regexp.MustCompile(`To:\s*"[^"]*"\s*<[^>]+>`)
+	toPattern       = regexp.MustCompile(`To:[ \t]*([^<\n]*?)[ \t]*<([^>]+)>`)
+)
+
+// AnonymizeContent replaces links and the addresses of forwarded headers in a body
+func AnonymizeContent(content string) string {
+	content = httpPattern.ReplaceAllString(content, "https://example.com")
+	content = fromPattern.ReplaceAllString(content, "From: Example <email@example.com>")
+	content = toQuotedPattern.ReplaceAllString(content, "To: Example <email@example.com>")
+	content = toPattern.ReplaceAllString(content, "To: Example <email@example.com>")
+
+	return content
+}
@@ -0,0 +1,90 @@
+package fixture
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	raw := "Subject: Fwd: You made a purchase.\r\n" +
+		"From: Jane Doe <jane@personal.example>\r\n" +
+		"To: parser@mydomain.example\r\n" +
+		"Date: Sat, 13 Sep 2025 10:00:00 -0600\r\n" +
+		"Content-Type: text/plain\r\n\r\n" +
+		"---------- Forwarded message ---------\r\n" +
+		"From: RBC Royal Bank <alerts@rbc.example>\r\n" +
+		"Date: Sat, Sep 13, 2025 at 9:57 AM\r\n" +
+		"Subject: You made a purchase.\r\n" +
+		"To: Jane Doe <jane@personal.example>\r\n\r\n" +
+		"A purchase of $39.50 was made on card 4510 1234 5678 9012, see https://rbc.example/alerts?id=123\r\n"
+
+	f, err := Decode([]byte(raw))
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+
+	if f.Name != "fwd-you-made-a-purchase" || f.Subject != "You made a purchase." || !strings.Contains(f.From, "alerts@rbc.example") {
+		t.Errorf("fixture = %q, %q, %q", f.Name, f.Subject, f.From)
+	}
+	for _, leak := range []string{"jane@personal.example", "alerts@rbc.example", "id=123", "Jane Doe", "9012"} {
+		if strings.Contains(string(f.Data), leak) {
+			t.Errorf("fixture still contains %q:\n%s", leak, f.Data)
+		}
+	}
+
+	dir := t.TempDir()
+	first, err := f.Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := f.Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(first) != "fwd-you-made-a-purchase.decoded.eml" || filepath.Base(second) != "fwd-you-made-a-purchase-1.decoded.eml" {
+		t.Errorf("paths = %s, %s; want the second one numbered", first, second)
+	}
+	if _, err := os.Stat(second); err != nil {
+		t.Error(err)
+	}
+}
+
+// names in the subject are anonymized like the body, in the file name too
+func TestDecodeSubject(t *testing.T) {
+	raw := "Subject: INTERAC e-Transfer: JOHN DOE sent you money\r\n" +
+		"From: Interac <notify@payments.example>\r\n" +
+		"Content-Type: text/plain\r\n\r\n" +
+		"Hi, JOHN DOE sent you $25.00 (CAD).\r\n"
+
+	f, err := Decode([]byte(raw))
+	if err != nil {
+		t.Fatalf("Decode returned error: %v", err)
+	}
+	for _, got := range []string{f.Name, f.Subject, string(f.Data)} {
+		if strings.Contains(strings.ToLower(got), "john") {
+			t.Errorf("fixture still names the payer: %q", got)
+		}
+	}
+	if findings := Default.Find(string(f.Data)); len(findings) > 0 {
+		t.Errorf("Find = %+v; want none", findings)
+	}
+	if !strings.HasPrefix(f.Name, "interac-e-transfer-") {
+		t.Errorf("Name = %q", f.Name)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"You made a purchase.":  "you-made-a-purchase",
+		"Re:  Payment -- Made!": "re-payment-made",
+		"":                      "no-subject",
+		"Dépôt reçu":            "dpt-reu",
+	}
+	for subject, want := range tests {
+		if got := Slug(subject); got != want {
+			t.Errorf("Slug(%q) = %q; want %q", subject, got, want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package heuristic extracts a transaction from a bank notification no parser
+// handles, by looking for an amount, a date, a masked account number and a merchant
+// anywhere in the text. the result is flagged low confidence, it gives a new bank
+// partial coverage until a real parser is written
+package heuristic
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"null-email-parser/internal/domain"
+	"null-email-parser/internal/parser"
+)
+
+// Extractor handles emails from allowlisted senders
+type Extractor struct {
+	Senders  []string // sender domains, subdomains included
+	Currency string   // of amounts that do not name theirs, e.g. "$12.00"
+}
+
+// New returns an extractor for the given sender domains, reading a bare "$" as
+// currency, "CAD" when empty
+func New(senders []string, currency string) *Extractor {
+	e := &Extractor{Currency: strings.ToUpper(strings.TrimSpace(currency))}
+	if e.Currency == "" {
+		e.Currency = "CAD"
+	}
+	for _, s := range senders {
+		if s = strings.ToLower(strings.Trim(strings.TrimSpace(s), "@.")); s != "" {
+			e.Senders = append(e.Senders, s)
+		}
+	}
+	return e
+}
+
+// Allowed reports whether the email comes from an allowlisted sender
+func (e *Extractor) Allowed(meta parser.EmailMeta) bool {
+	if e == nil {
+		return false
+	}
+	domain := meta.SenderDomain()
+	for _, s := range e.Senders {
+		if domain == s || strings.HasSuffix(domain, "."+s) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	amountPattern   = regexp.MustCompile(parser.MoneyPattern)
+	accountPattern  = regexp.MustCompile(`(?i)(\*{2,}\s?\d{3,4}|x{2,}\d{3,4})\b|\b(?:ending in|ending with)\s+(\d{3,4})\b`)
+	merchantPattern = regexp.MustCompile(`\b(?:at|to|towards|from|with)\s+([A-Z][A-Z0-9&'.*\-]+(?:\s[A-Z0-9&'.*#\-]+){0,5})\b`)
+	inPattern       = regexp.MustCompile(`(?i)\b(?:credited|deposit(?:ed)?|refund(?:ed)?|received|incoming|reimburse)`)
+	datePattern     = regexp.MustCompile(parser.DatePattern)
+)
+
+// Pattern marks the provenance of heuristic fields
+const Pattern = "heuristic"
+
+// Extract returns a low confidence transaction, or nil when the email is not from an
+// allowlisted sender or no amount is found in it
+func (e *Extractor) Extract(meta parser.EmailMeta) *domain.Transaction {
+	if !e.Allowed(meta) {
+		return nil
+	}
+	text := meta.Text
+
+	m := amountPattern.FindStringIndex(text)
+	if m == nil {
+		return nil
+	}
+	amount, currency, err := parser.ParseMoney(text[m[0]:m[1]], e.Currency)
+	if err != nil || amount.IsZero() {
+		return nil
+	}
+	direction := domain.Out
+	if amount.IsNegative() {
+		amount, direction = amount.Neg(), domain.In
+	}
+
+	// with a single amount to go by, the account is taken to be in its currency
+	txn := &domain.Transaction{
+		EmailID:         meta.ID,
+		TxBank:          bankName(meta.SenderDomain()),
+		TxAmount:        amount,
+		TxCurrency:      currency,
+		AccountCurrency: currency,
+		TxDirection:     direction,
+		TxDesc:          strings.TrimSpace(meta.Subject),
+		LowConfidence:   true,
+		Provenance:      map[string]domain.FieldSource{"amount": {Pattern: Pattern, Offset: m[0]}},
+	}
+	if inPattern.MatchString(meta.Subject) || inPattern.MatchString(text) {
+		txn.TxDirection = domain.In
+	}
+
+	// like parser.BuildTransaction, the email date is kept when it is the same day
+	// where the user is
+	loc := parser.DateLocation(meta, txn.TxBank)
+	received, ok := parser.ReceivedDate(meta, loc)
+	if !ok {
+		received = time.Now()
+	}
+	txn.TxDate = received
+	local := received.In(loc)
+	ctx := parser.DateContext{Location: loc, Order: parser.BankDateOrder(txn.TxBank), Received: received}
+	for _, dm := range datePattern.FindAllStringIndex(text, -1) {
+		t, confidence, err := parser.ParseDate(text[dm[0]:dm[1]], ctx)
+		if err != nil {
+			continue
+		}
+		if t.Year() != local.Year() || t.YearDay() != local.YearDay() {
+			txn.TxDate = t
+		}
+		txn.Provenance["txdate"] = domain.FieldSource{Pattern: Pattern, Offset: dm[0], Note: confidenceNote(confidence)}
+		break
+	}
+
+	if am := accountPattern.FindStringSubmatchIndex(text); am != nil {
+		// like parser.BuildTransaction, the words right before the account tell its kind
+		txn.AccountKind = parser.InferAccountKind(strings.ToValidUTF8(text[max(0, am[0]-80):am[1]], ""))
+		if am[2] >= 0 {
+			txn.TxAccount = strings.ReplaceAll(text[am[2]:am[3]], " ", "")
+			txn.Provenance["account"] = domain.FieldSource{Pattern: Pattern, Offset: am[2]}
+		} else {
+			txn.TxAccount = "****" + text[am[4]:am[5]]
+			txn.Provenance["account"] = domain.FieldSource{Pattern: Pattern, Offset: am[4]}
+		}
+	}
+
+	if mm := merchantPattern.FindStringSubmatchIndex(text); mm != nil {
+		txn.Merchant = strings.TrimSpace(text[mm[2]:mm[3]])
+		txn.TxDesc = txn.Merchant
+		txn.Provenance["desc"] = domain.FieldSource{Pattern: Pattern, Offset: mm[2]}
+	}
+
+	return txn
+}
+
+// confidenceNote is the provenance note of a parsed date, empty when it was certain
+func confidenceNote(c parser.DateConfidence) string {
+	if c == parser.DateCertain {
+		return ""
+	}
+	return c.String()
+}
+
+// bankName guesses a bank name from a sender domain, e.g. "alerts.td.com" gives "td"
+func bankName(domain string) string {
+	parts := strings.Split(domain, ".")
+	if len(parts) < 2 {
+		return domain
+	}
+	return parts[len(parts)-2]
+}
@@ -0,0 +1,127 @@
+package heuristic
+
+import (
+	"encoding/json"
+	"net/mail"
+	"os"
+	"testing"
+	"time"
+
+	"null-email-parser/internal/domain"
+	"null-email-parser/internal/parser"
+	"null-email-parser/internal/parser/parsertest"
+)
+
+func meta(from, subject, text string) parser.EmailMeta {
+	return parser.EmailMeta{
+		ID:          "test",
+		Subject:     subject,
+		Text:        text,
+		Date:        "2025-09-15T08:18:20-06:00",
+		FromAddress: &mail.Address{Address: from},
+	}
+}
+
+func TestExtract(t *testing.T) {
+	e := New([]string{"@newbank.example", ""}, "")
+
+	tests := []struct {
+		name, from, subject, text string
+		want                      *domain.Transaction
+	}{
+		{
+			name: "purchase", from: "alerts@mail.newbank.example", subject: "Card alert",
+			text: "A purchase of $1,234.56 was made on your card ending in 4821 at BEST BUY #123 on September 14, 2025.",
+			want: &domain.Transaction{TxBank: "newbank", TxAccount: "****4821", TxAmount: domain.MustDecimal("1234.56"), TxCurrency: "CAD",
+				TxDirection: domain.Out, TxDesc: "BEST BUY #123", Merchant: "BEST BUY #123",
+				TxDate: time.Date(2025, 9, 14, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "deposit in usd", from: "alerts@newbank.example", subject: "Deposit received",
+			text: "USD 50.00 was deposited to account ****0099.",
+			want: &domain.Transaction{TxBank: "newbank", TxAccount: "****0099", TxAmount: domain.MustDecimal("50"), TxCurrency: "USD",
+				TxDirection: domain.In, TxDesc: "Deposit received",
+				TxDate: time.Date(2025, 9, 15, 8, 18, 20, 0, time.FixedZone("", -6*3600))},
+		},
+		{
+			name: "not allowlisted", from: "alerts@otherbank.example", subject: "Card alert",
+			text: "A purchase of $12.00 was made.",
+		},
+		{
+			name: "no amount", from: "alerts@newbank.example", subject: "Your statement is ready",
+			text: "Your statement for September 2025 is ready.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := e.Extract(meta(tt.from, tt.subject, tt.text))
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("Extract = %+v; want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("Extract = nil")
+			}
+			if !got.LowConfidence {
+				t.Error("transaction not flagged low confidence")
+			}
+			if got.TxBank != tt.want.TxBank || got.TxAccount != tt.want.TxAccount || got.TxAmount != tt.want.TxAmount ||
+				got.TxCurrency != tt.want.TxCurrency || got.AccountCurrency != tt.want.TxCurrency || got.TxDirection != tt.want.TxDirection ||
+				got.TxDesc != tt.want.TxDesc || got.Merchant != tt.want.Merchant || !got.TxDate.Equal(tt.want.TxDate) {
+				t.Errorf("Extract = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// the extractor should get the amount and account of a real notification right
+// a bare "$" is in the currency configured for the senders, not always CAD
+func TestExtractCurrency(t *testing.T) {
+	e := New([]string{"usbank.example"}, "usd")
+
+	got := e.Extract(meta("alerts@usbank.example", "Card alert", "A purchase of $12.00 was made on your card ending in 4821."))
+	if got == nil || got.TxCurrency != "USD" || got.AccountCurrency != "USD" || got.ForeignAmount != nil {
+		t.Errorf("Extract = %+v; want a USD amount on a USD account", got)
+	}
+
+	got = e.Extract(meta("alerts@usbank.example", "Card alert", "A purchase of EUR 8.00 was made on your card ending in 4821."))
+	if got == nil || got.TxCurrency != "EUR" || got.AccountCurrency != "EUR" {
+		t.Errorf("Extract = %+v; want the currency the amount names", got)
+	}
+}
+
+func TestExtractFixture(t *testing.T) {
+	m := parsertest.LoadMeta(t, "../email/rbc/testdata/you-made-a-purchase.decoded.eml")
+
+	got := New([]string{"example.com"}, "CAD").Extract(m)
+	if got == nil {
+		t.Fatal("Extract = nil")
+	}
+	if got.TxAmount != domain.MustDecimal("1.77") || got.TxAccount != "************1001" || got.AccountKind != domain.CreditCard || got.TxDirection != domain.Out || got.Merchant != "TIM HORTONS #0000" ||
+		got.TxDate.Format(time.RFC3339) != "2025-09-15T08:18:20-06:00" {
+		t.Errorf("Extract = %+v", got)
+	}
+}
+
+func TestHold(t *testing.T) {
+	r := &Review{Dir: t.TempDir()}
+	path, err := r.Hold("user-1", &domain.Transaction{EmailID: "a/b", TxAmount: domain.MustDecimal("12.5"), LowConfidence: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var held Held
+	if err := json.Unmarshal(data, &held); err != nil {
+		t.Fatal(err)
+	}
+	if held.UserUUID != "user-1" || held.Transaction.TxAmount != domain.MustDecimal("12.5") || !held.Transaction.LowConfidence {
+		t.Errorf("held = %+v", held)
+	}
+}
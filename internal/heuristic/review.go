@@ -0,0 +1,55 @@
+package heuristic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"null-email-parser/internal/domain"
+)
+
+// Review holds low confidence transactions as json files for a person to check,
+// instead of creating them
+type Review struct {
+	Dir string
+}
+
+// Held is a transaction waiting for review
+type Held struct {
+	UserUUID    string              `json:"user_uuid"`
+	Held        time.Time           `json:"held"`
+	Transaction *domain.Transaction `json:"transaction"`
+}
+
+// Hold writes a transaction to the review directory and returns its path
+func (r *Review) Hold(userUUID string, txn *domain.Transaction) (string, error) {
+	if err := os.MkdirAll(r.Dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create review directory: %w", err)
+	}
+
+	held := Held{UserUUID: userUUID, Held: time.Now().UTC(), Transaction: txn}
+	data, err := json.MarshalIndent(held, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s_%s_%s.json", held.Held.Format("20060102-150405"), userUUID, safeName(txn.EmailID))
+	path := filepath.Join(r.Dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write held transaction: %w", err)
+	}
+	return path, nil
+}
+
+// safeName keeps the letters, digits and dashes of s
+func safeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"null-email-parser/internal/domain"
+)
+
+// accountKinds are the words emails use for each kind of account, english and french
+var accountKinds = []struct {
+	kind domain.AccountKind
+	re   *regexp.Regexp
+}{
+	// debit cards carry the network's name, "Visa Debit" is the chequing account
+	{domain.Chequing, regexp.MustCompile(`(?i)\b(?:visa|mastercard)\s+debit\b|\bdebit[ _]card\b|\bcarte de débit\b`)},
+	{domain.LineOfCredit, regexp.MustCompile(`(?i)\bline[ _]of[ _]credit\b|\bcredit[ _]line\b|\bmarge de crédit\b|(?-i:\bLOC\b)`)},
+	{domain.CreditCard, regexp.MustCompile(`(?i)\bcredit[ _]card\b|\bcarte de crédit\b|\bvisa\b|\bmastercard\b|\bamex\b|\bamerican express\b`)},
+	{domain.Savings, regexp.MustCompile(`(?i)\bsavings?\b|épargne\b`)},
+	{domain.Chequing, regexp.MustCompile(`(?i)\bche(?:que|quing|cking)\b|\bchèques?\b|\bcurrent account\b`)},
+	{domain.Investment, regexp.MustCompile(`(?i)\binvest(?:ment|ing)?\b|\bbrokerage\b|\bTFSA\b|\bRRSP\b|\bRESP\b|\bCELI\b|\bREER\b`)},
+}
+
+// InferAccountKind returns the kind of account text is about, the last one mentioned
+// when there are several, so the words right before an account number win. of two
+// matches at the same place, the one listed first in accountKinds wins
+func InferAccountKind(text string) domain.AccountKind {
+	kind, last := domain.UnknownAccount, -1
+	for _, k := range accountKinds {
+		for _, m := range k.re.FindAllStringIndex(text, -1) {
+			if m[0] > last {
+				kind, last = k.kind, m[0]
+			}
+		}
+	}
+	return kind
+}
+
+// accountContext is how much text before the account number describes it, e.g.
+// "your RBC Royal Bank credit card account ************1001"
+const accountContext = 80
+
+// accountKind returns the kind given by the "account_kind" field, or else inferred
+// from the text right before the account and the account itself
+func accountKind(m EmailMeta, fields Fields) domain.AccountKind {
+	if raw := fields.Get("account_kind"); raw != "" {
+		return InferAccountKind(raw)
+	}
+
+	account := fields["account"]
+	if account.Value == "" {
+		return domain.UnknownAccount
+	}
+	end := account.Offset + len(account.Value)
+	if account.Offset < 0 || end > len(m.Text) || m.Text[account.Offset:end] != account.Value {
+		// found in the html or a label, there is no context to look at
+		return InferAccountKind(account.Value)
+	}
+
+	start := max(0, account.Offset-accountContext)
+	return InferAccountKind(strings.ToValidUTF8(m.Text[start:end], ""))
+}
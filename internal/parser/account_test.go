@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"regexp"
+	"testing"
+
+	"null-email-parser/internal/domain"
+)
+
+func TestInferAccountKind(t *testing.T) {
+	tests := map[string]domain.AccountKind{
+		"your RBC Royal Bank credit card account ************1001": domain.CreditCard,
+		"your Visa ending in 4821":                                 domain.CreditCard,
+		"your RBC Visa Debit card ending in 4821":                  domain.Chequing,
+		"your debit card":                                          domain.Chequing,
+		"your bank account Savings":                                domain.Savings,
+		"from your chequing account 1234 to your savings account":  domain.Savings,
+		"your line of credit ****5678":                             domain.LineOfCredit,
+		"a credit line":                                            domain.LineOfCredit,
+		"votre compte d'épargne":                                   domain.Savings,
+		"votre carte de crédit":                                    domain.CreditCard,
+		"your TFSA":                                                domain.Investment,
+		"credit_card":                                              domain.CreditCard,
+		"your bank account Daily":                                  domain.UnknownAccount,
+		"your location":                                            domain.UnknownAccount,
+	}
+	for text, want := range tests {
+		if got := InferAccountKind(text); got != want {
+			t.Errorf("InferAccountKind(%q) = %q; want %q", text, got, want)
+		}
+	}
+}
+
+// the kind comes from right before the account, not from the rest of the email
+func TestBuildTransactionAccountKind(t *testing.T) {
+	text := "Your chequing account was debited. A purchase of USD 12.00 was made on your credit card ****1001. " +
+		"Questions about your savings? Call us."
+	fields, err := ExtractFields(text, map[string]*regexp.Regexp{
+		"account": regexp.MustCompile(`(\*+\d+)`),
+		"amount":  regexp.MustCompile(`(` + MoneyPattern + `)`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields["txdate"] = Field{Value: "September 13, 2025"}
+
+	txn, err := BuildTransaction(EmailMeta{Text: text}, fields, "bank", "CAD", "out", "desc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.AccountKind != domain.CreditCard || txn.AccountCurrency != "CAD" {
+		t.Errorf("account = %q in %q; want a CAD credit card, the purchase was abroad", txn.AccountKind, txn.AccountCurrency)
+	}
+
+	fields["account_currency"] = Field{Value: "usd"}
+	if txn, err = BuildTransaction(EmailMeta{Text: text}, fields, "bank", "CAD", "out", "desc"); err != nil || txn.AccountCurrency != "USD" || txn.ForeignAmount != nil {
+		t.Errorf("account currency = %q, foreign %v, %v; want a USD account charged in USD", txn.AccountCurrency, txn.ForeignAmount, err)
+	}
+	fields["account_currency"] = Field{Value: "dollars"}
+	if _, err := BuildTransaction(EmailMeta{Text: text}, fields, "bank", "CAD", "out", "desc"); err == nil {
+		t.Error("account_currency that is no currency code accepted")
+	}
+	delete(fields, "account_currency")
+
+	fields["account_kind"] = Field{Value: "Line of Credit"}
+	if txn, err = BuildTransaction(EmailMeta{Text: text}, fields, "bank", "CAD", "out", "desc"); err != nil || txn.AccountKind != domain.LineOfCredit {
+		t.Errorf("AccountKind = %q, %v; want the account_kind field", txn.AccountKind, err)
+	}
+}
@@ -0,0 +1,245 @@
+package parser
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DateOrder is how a bank writes numeric dates such as 03/04/2025
+type DateOrder int
+
+const (
+	MonthFirst DateOrder = iota // 03/04/2025 is March 4th, the default
+	DayFirst                    // 03/04/2025 is April 3rd
+)
+
+// DateConfidence tells how much of a parsed date was guessed, higher is less certain
+type DateConfidence int
+
+const (
+	DateCertain      DateConfidence = iota
+	DateYearInferred                // no year was written, it was taken from the received date
+	DateOrderAssumed                // day and month could be swapped, the bank's order was used
+)
+
+func (c DateConfidence) String() string {
+	switch c {
+	case DateYearInferred:
+		return "year inferred"
+	case DateOrderAssumed:
+		return "day and month order assumed"
+	}
+	return "certain"
+}
+
+var (
+	ordersMu   sync.RWMutex
+	bankOrders = map[string]DateOrder{}
+)
+
+// RegisterDateOrder sets how a bank writes numeric dates, MonthFirst when not set
+func RegisterDateOrder(bank string, order DateOrder) {
+	ordersMu.Lock()
+	defer ordersMu.Unlock()
+	bankOrders[strings.ToLower(bank)] = order
+}
+
+// BankDateOrder returns the numeric date order of a bank
+func BankDateOrder(bank string) DateOrder {
+	ordersMu.RLock()
+	defer ordersMu.RUnlock()
+	return bankOrders[strings.ToLower(bank)]
+}
+
+// months maps english and french month names and abbreviations to months
+var months = func() map[string]time.Month {
+	names := map[time.Month][]string{
+		time.January:   {"january", "jan", "janvier", "janv"},
+		time.February:  {"february", "feb", "février", "fevrier", "févr", "fevr", "fév", "fev"},
+		time.March:     {"march", "mar", "mars"},
+		time.April:     {"april", "apr", "avril", "avr"},
+		time.May:       {"may", "mai"},
+		time.June:      {"june", "jun", "juin"},
+		time.July:      {"july", "jul", "juillet", "juil"},
+		time.August:    {"august", "aug", "août", "aout"},
+		time.September: {"september", "sep", "sept", "septembre"},
+		time.October:   {"october", "oct", "octobre"},
+		time.November:  {"november", "nov", "novembre"},
+		time.December:  {"december", "dec", "décembre", "decembre", "déc"},
+	}
+	out := map[string]time.Month{}
+	for month, list := range names {
+		for _, name := range list {
+			out[name] = month
+		}
+	}
+	return out
+}()
+
+var weekdays = []string{
+	"monday", "mon", "tuesday", "tues", "tue", "wednesday", "wed", "thursday", "thurs", "thur", "thu",
+	"friday", "fri", "saturday", "sat", "sunday", "sun",
+	"lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi", "dimanche",
+}
+
+// dateNoise holds the weekdays and filler words skipped in textual dates
+var dateNoise = func() map[string]bool {
+	out := map[string]bool{"on": true, "the": true, "of": true, "le": true, "de": true, "du": true}
+	for _, day := range weekdays {
+		out[day] = true
+	}
+	return out
+}()
+
+// alternation matches any of names, longest first so "sept" wins over "sep"
+func alternation(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = regexp.QuoteMeta(name)
+	}
+	slices.SortFunc(quoted, func(a, b string) int { return cmp.Or(cmp.Compare(len(b), len(a)), cmp.Compare(a, b)) })
+	return `(?:` + strings.Join(quoted, "|") + `)`
+}
+
+// DatePattern matches a date in any form ParseDate reads: "2025-09-13", "09/13/2025",
+// "Sep 13, 2025", "Saturday, September 13th", "13 septembre 2025" or "le 1er oct.".
+// it has no capture group, wrap it in one for ExtractFields like MoneyPattern
+var DatePattern = func() string {
+	month := alternation(slices.Collect(maps.Keys(months))) + `\b\.?`
+	weekday := `(?:(?:on\s+)?` + alternation(weekdays) + `\b\.?,?\s+)?`
+	return `(?i:\b(?:` +
+		`\d{4}[-/.]\d{1,2}[-/.]\d{1,2}\b` +
+		`|\d{1,2}[-/.]\d{1,2}[-/.](?:\d{4}|\d{2})\b` +
+		`|` + weekday + month + `\s+\d{1,2}(?:st|nd|rd|th)?\b(?:,?\s+\d{4}\b)?` +
+		`|` + weekday + `(?:le\s+|the\s+)?\d{1,2}(?:er|st|nd|rd|th)?\s+(?:of\s+|de\s+)?` + month + `(?:,?\s+\d{4}\b)?` +
+		`))`
+}()
+
+// DateContext is what ParseDate needs to resolve a date written ambiguously
+type DateContext struct {
+	Location *time.Location // dates are midnight in this zone, UTC when nil
+	Order    DateOrder      // of numeric dates like 03/04/2025
+	Received time.Time      // when the email was sent, for dates without a year
+}
+
+var (
+	isoDate     = regexp.MustCompile(`^(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})$`)
+	numericDate = regexp.MustCompile(`^(\d{1,2})[-/.](\d{1,2})(?:[-/.](\d{4}|\d{2}))?$`)
+	ordinal     = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th|er|e)$`)
+)
+
+// ParseDate reads a date in the forms listed for DatePattern, numeric ones in the
+// order of ctx, and returns it as midnight in ctx.Location along with how much of it
+// was guessed
+func ParseDate(raw string, ctx DateContext) (time.Time, DateConfidence, error) {
+	loc := ctx.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	s := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+
+	var (
+		year, day  int
+		month      time.Month
+		confidence = DateCertain
+	)
+	if m := isoDate.FindStringSubmatch(s); m != nil {
+		year, month, day = atoi(m[1]), time.Month(atoi(m[2])), atoi(m[3])
+	} else if m := numericDate.FindStringSubmatch(s); m != nil {
+		a, b := atoi(m[1]), atoi(m[2])
+		switch {
+		case a > 12:
+			day, month = a, time.Month(b)
+		case b > 12:
+			month, day = time.Month(a), b
+		case ctx.Order == DayFirst:
+			day, month = a, time.Month(b)
+		default:
+			month, day = time.Month(a), b
+		}
+		if a != b && a <= 12 && b <= 12 {
+			confidence = DateOrderAssumed
+		}
+		if m[3] != "" {
+			year = atoi(m[3])
+			if len(m[3]) == 2 {
+				year += 2000
+			}
+		}
+	} else {
+		var err error
+		if year, month, day, err = textualDate(s); err != nil {
+			return time.Time{}, DateCertain, fmt.Errorf("unrecognized date %q: %w", raw, err)
+		}
+	}
+
+	if year == 0 {
+		year = inferYear(month, day, ctx.Received, loc)
+		confidence = max(confidence, DateYearInferred)
+	}
+
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if month < time.January || month > time.December || t.Day() != day || t.Month() != month {
+		return time.Time{}, DateCertain, fmt.Errorf("unrecognized date %q: no such day", raw)
+	}
+	return t, confidence, nil
+}
+
+// textualDate reads a date with a month name, the year is 0 when there is none
+func textualDate(s string) (year int, month time.Month, day int, err error) {
+	s = strings.NewReplacer(",", " ", ".", " ").Replace(s)
+	for _, token := range strings.Fields(s) {
+		if m, ok := months[token]; ok && month == 0 {
+			month = m
+			continue
+		}
+		if dateNoise[token] {
+			continue
+		}
+		if m := ordinal.FindStringSubmatch(token); m != nil {
+			token = m[1]
+		}
+		n, convErr := strconv.Atoi(token)
+		switch {
+		case convErr != nil:
+			return 0, 0, 0, fmt.Errorf("unexpected %q", token)
+		case len(token) == 4 && year == 0:
+			year = n
+		case len(token) <= 2 && day == 0:
+			day = n
+		default:
+			return 0, 0, 0, fmt.Errorf("unexpected %q", token)
+		}
+	}
+	if month == 0 || day == 0 {
+		return 0, 0, 0, fmt.Errorf("no month or day")
+	}
+	return year, month, day, nil
+}
+
+// inferYear returns the year that puts the date closest before received, a notification
+// received on January 2nd for "December 31" is about last year. a date up to a week
+// after received is still taken as this year
+func inferYear(month time.Month, day int, received time.Time, loc *time.Location) int {
+	if received.IsZero() {
+		received = time.Now()
+	}
+	received = received.In(loc)
+	year := received.Year()
+	if time.Date(year, month, day, 0, 0, 0, 0, loc).After(received.AddDate(0, 0, 7)) {
+		year--
+	}
+	return year
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	received := time.Date(2025, 9, 15, 12, 0, 0, 0, time.UTC)
+	monthFirst := DateContext{Received: received}
+	dayFirst := DateContext{Received: received, Order: DayFirst}
+
+	tests := []struct {
+		in         string
+		ctx        DateContext
+		want       string
+		confidence DateConfidence
+	}{
+		{"September 13, 2025", monthFirst, "2025-09-13", DateCertain},
+		{"2025-09-13", monthFirst, "2025-09-13", DateCertain},
+		{"2025/9/3", dayFirst, "2025-09-03", DateCertain},
+		{"09/13/2025", monthFirst, "2025-09-13", DateCertain},
+		{"13/09/2025", monthFirst, "2025-09-13", DateCertain},
+		{"03/04/2025", monthFirst, "2025-03-04", DateOrderAssumed},
+		{"03/04/2025", dayFirst, "2025-04-03", DateOrderAssumed},
+		{"04.04.25", dayFirst, "2025-04-04", DateCertain},
+		{"Sep 13, 2025", monthFirst, "2025-09-13", DateCertain},
+		{"Sept. 13 2025", monthFirst, "2025-09-13", DateCertain},
+		{"Saturday, September 13th, 2025", monthFirst, "2025-09-13", DateCertain},
+		{"the 1st of October 2025", monthFirst, "2025-10-01", DateCertain},
+		{"13 septembre 2025", monthFirst, "2025-09-13", DateCertain},
+		{"le 1er févr. 2025", monthFirst, "2025-02-01", DateCertain},
+		{"samedi 13 août 2025", monthFirst, "2025-08-13", DateCertain},
+		{"September 13", monthFirst, "2025-09-13", DateYearInferred},
+		{"Sep 20", monthFirst, "2025-09-20", DateYearInferred}, // a few days ahead, still this year
+		{"December 31", monthFirst, "2024-12-31", DateYearInferred},
+		{"03/04", dayFirst, "2025-04-03", DateOrderAssumed},
+	}
+	for _, tt := range tests {
+		got, confidence, err := ParseDate(tt.in, tt.ctx)
+		if err != nil || got.Format("2006-01-02") != tt.want || confidence != tt.confidence {
+			t.Errorf("ParseDate(%q) = %s, %v, %v; want %s, %v", tt.in, got.Format("2006-01-02"), confidence, err, tt.want, tt.confidence)
+		}
+	}
+
+	for _, bad := range []string{"", "September", "February 30, 2025", "13/13/2025", "September 13, 2025 2:57 PM", "Smarch 13, 2025"} {
+		if got, _, err := ParseDate(bad, monthFirst); err == nil {
+			t.Errorf("ParseDate(%q) = %v; want an error", bad, got)
+		}
+	}
+}
+
+func TestParseDateLocation(t *testing.T) {
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := ParseDate("September 13, 2025", DateContext{Location: toronto})
+	if err != nil || !got.Equal(time.Date(2025, 9, 13, 0, 0, 0, 0, toronto)) {
+		t.Errorf("ParseDate = %v, %v; want midnight in toronto", got, err)
+	}
+}
+
+func TestDatePattern(t *testing.T) {
+	re := regexp.MustCompile(`(` + DatePattern + `)`)
+	tests := map[string]string{
+		"was made on September 13, 2025 towards": "September 13, 2025",
+		"on Saturday, September 13th at 2:57 PM": "on Saturday, September 13th",
+		"Date: 2025-09-13 12:00":                 "2025-09-13",
+		"posted 13/09/2025.":                     "13/09/2025",
+		"effectuée le 1er oct. 2025 chez":        "le 1er oct. 2025",
+		"made Sep 3":                             "Sep 3",
+	}
+	for text, want := range tests {
+		m := re.FindStringSubmatch(text)
+		if m == nil || m[1] != want {
+			t.Errorf("DatePattern in %q = %v; want %q", text, m, want)
+			continue
+		}
+		if _, _, err := ParseDate(m[1], DateContext{}); err != nil {
+			t.Errorf("ParseDate(%q): %v", m[1], err)
+		}
+	}
+	for _, text := range []string{"paid $12.50 for 3 items, ref 1234-5678", "ref 13/09/20251", "ref 2025-09-131"} {
+		if m := re.FindString(text); m != "" {
+			t.Errorf("DatePattern in %q matched %q", text, m)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// ParseError describes which field of which parser could not be extracted, with enough
+// context to diagnose template drift without keeping the email
+type ParseError struct {
+	Parser  string // parser name, filled in by the caller when the parser does not know it
+	Field   string // field that failed, e.g. "amount"
+	Pattern string // regex or label that was looked for
+	Value   string // raw value that was found but could not be converted, if any
+	Partial string // how much of the pattern did match, e.g. "2/4 pattern parts"
+	Excerpt string // redacted text around the closest partial match
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.Parser != "" {
+		fmt.Fprintf(&b, "parser %s: ", e.Parser)
+	}
+
+	switch {
+	case e.Field == "" && e.Err != nil:
+		fmt.Fprintf(&b, "%v", e.Err) // not about a single field, e.g. a script error
+	case e.Err != nil:
+		fmt.Fprintf(&b, "field %q: %v", e.Field, e.Err)
+	default:
+		fmt.Fprintf(&b, "field %q not found in text", e.Field)
+	}
+
+	if e.Pattern != "" {
+		fmt.Fprintf(&b, " (pattern %q", e.Pattern)
+		if e.Partial != "" {
+			fmt.Fprintf(&b, ", matched %s", e.Partial)
+		}
+		b.WriteString(")")
+	}
+
+	return b.String()
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// LogValues returns the error as key/value pairs for structured logging. the excerpt
+// and value are left out, they are still email content even when redacted
+func (e *ParseError) LogValues() []any {
+	return []any{
+		"parser", e.Parser,
+		"field", e.Field,
+		"pattern", e.Pattern,
+		"partial", e.Partial,
+	}
+}
+
+// Name returns a readable name for a parser, e.g. "rbc.purchase". parsers can
+// provide their own with a Name() string method or the ID of their Info
+func Name(p any) string {
+	if s, ok := p.(single); ok {
+		p = s.Parser
+	}
+	if d, ok := p.(Describer); ok {
+		if id := d.Describe().ID; id != "" {
+			return id
+		}
+	}
+	if named, ok := p.(interface{ Name() string }); ok {
+		return named.Name()
+	}
+
+	t := reflect.TypeOf(p)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return ""
+	}
+
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg == "" {
+		return t.Name()
+	}
+	return pkg + "." + t.Name()
+}
+
+// notFound builds the ParseError for a regex that did not match, locating the longest
+// leading part of the pattern that does match
+func notFound(field string, re *regexp.Regexp, text string) *ParseError {
+	perr := &ParseError{Field: field, Pattern: re.String()}
+
+	offset := 0
+	if done, total, at := partialMatch(re, text); done > 0 {
+		perr.Partial = fmt.Sprintf("%d/%d pattern parts", done, total)
+		offset = at
+	}
+	perr.Excerpt = excerpt(text, offset)
+
+	return perr
+}
+
+// partialMatch tries ever shorter prefixes of a concatenated pattern and reports how many
+// parts of it matched, out of how many, and where
+func partialMatch(re *regexp.Regexp, text string) (done, total, offset int) {
+	parsed, err := syntax.Parse(re.String(), syntax.Perl)
+	if err != nil {
+		return 0, 0, 0
+	}
+	parsed = parsed.Simplify()
+
+	parts := []*syntax.Regexp{parsed}
+	if parsed.Op == syntax.OpConcat {
+		parts = parsed.Sub
+	}
+
+	for n := len(parts) - 1; n > 0; n-- {
+		prefix := &syntax.Regexp{Op: syntax.OpConcat, Sub: parts[:n]}
+		partial, err := regexp.Compile(prefix.String())
+		if err != nil {
+			continue
+		}
+		if loc := partial.FindStringIndex(text); loc != nil {
+			return n, len(parts), loc[0]
+		}
+	}
+
+	return 0, len(parts), 0
+}
+
+const excerptRadius = 60
+
+// excerpt returns a redacted window of text around offset
+func excerpt(text string, offset int) string {
+	start := max(offset-excerptRadius/2, 0)
+	end := min(offset+excerptRadius*3/2, len(text))
+
+	// do not cut runes in half
+	for start > 0 && !isRuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !isRuneStart(text[end]) {
+		end++
+	}
+
+	return Redact(strings.Join(strings.Fields(text[start:end]), " "))
+}
+
+func isRuneStart(b byte) bool { return b&0xC0 != 0x80 }
+
+var (
+	redactEmail = regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)
+	redactDigit = regexp.MustCompile(`\d`)
+)
+
+// Redact masks what could identify a person or account in text that ends up in logs:
+// email addresses and every digit. the shape of the text (where amounts and dates are)
+// stays visible
+func Redact(text string) string {
+	text = redactEmail.ReplaceAllString(text, "<email>")
+	return redactDigit.ReplaceAllString(text, "#")
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestExtractFieldsParseError(t *testing.T) {
+	text := "a purchase of $1,234.56 was made on your card ************1001 towards TIM HORTONS."
+	patterns := map[string]*regexp.Regexp{
+		"account": regexp.MustCompile(`(\*{12}\d+)`),
+		"amount":  regexp.MustCompile(`purchase of \$(\d+\.\d{2}) was`),
+	}
+
+	_, err := ExtractFields(text, patterns)
+
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("err = %v; want a *ParseError", err)
+	}
+	if perr.Field != "amount" || perr.Pattern != patterns["amount"].String() {
+		t.Errorf("ParseError = %+v", perr)
+	}
+	if perr.Partial == "" {
+		t.Error("Partial is empty; the literal prefix of the pattern does match")
+	}
+	if !strings.Contains(perr.Excerpt, "purchase of $#,###.##") {
+		t.Errorf("Excerpt = %q; want the redacted text around the partial match", perr.Excerpt)
+	}
+	if strings.ContainsAny(perr.Excerpt, "0123456789") {
+		t.Errorf("Excerpt = %q leaks digits", perr.Excerpt)
+	}
+
+	perr.Parser = "rbc.purchase"
+	if got := perr.Error(); !strings.HasPrefix(got, `parser rbc.purchase: field "amount" not found in text`) {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestExtractFieldsProvenance(t *testing.T) {
+	text := "paid $39.50 on September 13, 2025"
+	fields, err := ExtractFields(text, map[string]*regexp.Regexp{
+		"account": regexp.MustCompile(`(\*+\d+)`),
+		"amount":  regexp.MustCompile(`\$([0-9,]+\.\d{2})`),
+		"txdate":  regexp.MustCompile(`([A-Za-z]+ \d{1,2}, \d{4})`),
+	})
+	if err != nil {
+		t.Fatalf("ExtractFields returned error: %v", err)
+	}
+
+	if got := fields["amount"]; got.Value != "39.50" || got.Offset != 6 || got.Pattern != `\$([0-9,]+\.\d{2})` {
+		t.Errorf("amount = %+v", got)
+	}
+	if got := fields["account"]; got.Value != "" || got.Offset != -1 {
+		t.Errorf("optional account = %+v; want empty with offset -1", got)
+	}
+
+	txn, err := BuildTransaction(EmailMeta{}, fields, "bank", "CAD", "out", "desc")
+	if err != nil {
+		t.Fatalf("BuildTransaction returned error: %v", err)
+	}
+	if src := txn.Provenance["txdate"]; src.Offset != strings.Index(text, "September") {
+		t.Errorf("txdate provenance = %+v", src)
+	}
+}
+
+func TestBuildTransactionConversionError(t *testing.T) {
+	fields := Fields{
+		"amount": {Value: "1.2.3", Pattern: `(\S+)`, Offset: 0},
+		"txdate": {Value: "September 13, 2025", Pattern: `(.+)`, Offset: 0},
+	}
+
+	_, err := BuildTransaction(EmailMeta{}, fields, "bank", "CAD", "out", "desc")
+
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Field != "amount" || perr.Value != "1.2.3" || perr.Err == nil {
+		t.Errorf("err = %#v; want a ParseError for amount wrapping the conversion error", err)
+	}
+}
+
+func TestName(t *testing.T) {
+	if got := Name(AsMulti(fakeParser{})); got != "parser.fakeParser" {
+		t.Errorf("Name() = %q", got)
+	}
+	if got := Name(&transferParser{}); got != "parser.transferParser" {
+		t.Errorf("Name() = %q", got)
+	}
+}
@@ -1,82 +1,136 @@
 package parser
 
 import (
-	"fmt"
+	"errors"
+	"maps"
 	"regexp"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
 	"null-email-parser/internal/domain"
 )
 
+// Field is a value extracted from an email along with where it came from
+type Field struct {
+	Value   string
+	Pattern string // regex or label that produced the value
+	Offset  int    // byte offset of the value in the text, -1 if not applicable
+}
+
+// Fields holds extracted values by key
+type Fields map[string]Field
+
+// Get returns the value of a field, "" if it is missing
+func (f Fields) Get(key string) string { return f[key].Value }
+
+// Provenance returns where each field came from, for domain.Transaction
+func (f Fields) Provenance() map[string]domain.FieldSource {
+	out := make(map[string]domain.FieldSource, len(f))
+	for key, field := range f {
+		out[key] = domain.FieldSource{Pattern: field.Pattern, Offset: field.Offset}
+	}
+	return out
+}
+
+// optionalFields are set to "" when not found, the others are required
+var optionalFields = map[string]bool{
+	"account": true, "account_kind": true, "account_currency": true, "txtime": true, "foreign_amount": true, "exchange_rate": true,
+}
+
 // ExtractFields applies each regex to the email body and returns the single capture group for each key
-// The "account" field is optional - if not found, it will be set to empty string
-func ExtractFields(emailBody string, patterns map[string]*regexp.Regexp) (map[string]string, error) {
-	out := make(map[string]string, len(patterns))
-	for key, re := range patterns {
-		m := re.FindStringSubmatch(emailBody)
-		if len(m) < 2 {
-			if key == "account" {
-				out[key] = ""
+// The "account", "account_kind", "account_currency", "txtime", "foreign_amount" and "exchange_rate" fields are optional - if not found, they will be set to empty string
+// A missing field is reported as a *ParseError
+func ExtractFields(emailBody string, patterns map[string]*regexp.Regexp) (Fields, error) {
+	out := make(Fields, len(patterns))
+	for _, key := range slices.Sorted(maps.Keys(patterns)) {
+		re := patterns[key]
+		m := re.FindStringSubmatchIndex(emailBody)
+		if len(m) < 4 || m[2] < 0 {
+			if optionalFields[key] {
+				out[key] = Field{Pattern: re.String(), Offset: -1}
 				continue
 			}
-			return nil, fmt.Errorf("field %q not found in text", key)
+			return nil, notFound(key, re, emailBody)
 		}
-		out[key] = m[1]
+		out[key] = Field{Value: emailBody[m[2]:m[3]], Pattern: re.String(), Offset: m[2]}
 	}
 
 	return out, nil
 }
 
-// ParseEmailDate normalizes a string into time.Time
-func ParseEmailDate(raw string) (time.Time, error) {
-	return time.Parse("January 2, 2006", raw)
-}
-
-// BuildTransaction assembles a domain.Transaction
+// BuildTransaction assembles a domain.Transaction. dates are read with ParseDate in the
+// zone of the user or bank, see DateLocation, and the optional "txtime" field gives the
+// time of day. purchases in another currency are described by the optional
+// "foreign_amount" and "exchange_rate" fields, see setForeign. the kind of account is
+// the optional "account_kind" field, or inferred from the words before the account.
+// the account is kept in currency unless the optional "account_currency" field says
+// otherwise, e.g. "USD" for a US dollar card
 func BuildTransaction(
 	m EmailMeta,
-	fields map[string]string,
+	fields Fields,
 	bank string,
 	currency string,
 	dir domain.Direction,
 	desc string,
 ) (*domain.Transaction, error) {
 
-	recv := time.Now()
-	if m.Date != "" {
-		if parsed, err := time.Parse(time.RFC3339, m.Date); err == nil {
-			recv = parsed
-		} else if parsed, err := time.Parse("Mon, 2 Jan 2006 15:04:05 -0700", m.Date); err == nil {
-			recv = parsed
-		}
+	loc := DateLocation(m, bank)
+	recv, ok := ReceivedDate(m, loc)
+	if !ok {
+		recv = time.Now()
 	}
 
-	bodyDate, err := ParseEmailDate(fields["txdate"])
+	bodyDate, confidence, err := ParseDate(fields.Get("txdate"), DateContext{Location: loc, Order: BankDateOrder(bank), Received: recv})
 	if err != nil {
-		return nil, err
+		return nil, conversionError("txdate", fields["txdate"], err)
+	}
+	provenance := fields.Provenance()
+	if confidence != DateCertain {
+		src := provenance["txdate"]
+		src.Note = confidence.String()
+		provenance["txdate"] = src
 	}
 
-	// decide which timestamp to keep
+	// decide which timestamp to keep: the time in the body, else the receive time when
+	// it is the same day where the user is, else midnight of the body date
 	var final time.Time
-	if recv.Year() == bodyDate.Year() && recv.YearDay() == bodyDate.YearDay() {
+	local := recv.In(loc)
+	switch {
+	case fields.Get("txtime") != "":
+		clock, err := ParseClock(fields.Get("txtime"))
+		if err != nil {
+			return nil, conversionError("txtime", fields["txtime"], err)
+		}
+		final = time.Date(bodyDate.Year(), bodyDate.Month(), bodyDate.Day(), 0, 0, int(clock.Seconds()), 0, loc)
+	case local.Year() == bodyDate.Year() && local.YearDay() == bodyDate.YearDay():
 		final = recv
-	} else {
+	default:
 		final = bodyDate
 	}
 
-	rawAmt := strings.ReplaceAll(fields["amount"], ",", "")
-	amt, err := strconv.ParseFloat(rawAmt, 64)
+	// the account's currency is the fallback for amounts that do not name theirs
+	home := currency
+	if raw := fields.Get("account_currency"); raw != "" {
+		home = strings.ToUpper(strings.TrimSpace(raw))
+		if !currencyCode.MatchString(home) {
+			return nil, conversionError("account_currency", fields["account_currency"], errors.New("not an ISO 4217 code"))
+		}
+	}
+	amt, currency, err := ParseMoney(fields.Get("amount"), home)
 	if err != nil {
-		return nil, err
+		return nil, conversionError("amount", fields["amount"], err)
+	}
+	if amt.IsNegative() {
+		// a negative amount reverses the transaction, e.g. a refunded purchase
+		amt, dir = amt.Neg(), reverse(dir)
 	}
 
-	return &domain.Transaction{
+	txn := &domain.Transaction{
 		EmailID:         m.ID,
 		TxDate:          final,
 		TxBank:          bank,
-		TxAccount:       fields["account"],
+		TxAccount:       fields.Get("account"),
 		TxAmount:        amt,
 		TxCurrency:      currency,
 		TxDirection:     dir,
@@ -86,5 +140,107 @@ func BuildTransaction(
 		ForeignAmount:   nil,
 		ForeignCurrency: nil,
 		ExchangeRate:    nil,
-	}, nil
+		Provenance:      provenance,
+	}
+	if err := setForeign(txn, fields, home); err != nil {
+		return nil, err
+	}
+	txn.AccountKind = accountKind(m, fields)
+	txn.AccountCurrency = home // a purchase abroad does not change the card's currency
+	return txn, nil
+}
+
+// setForeign fills the foreign amount and exchange rate of a transaction made in
+// another currency. "foreign_amount" is the amount before conversion, the rate is then
+// computed when the email does not give it. without it, an amount in another currency
+// than home is converted to home with "exchange_rate", e.g. "USD 12.00" at 1.3767, or
+// only marked foreign when there is no rate either
+func setForeign(txn *domain.Transaction, fields Fields, home string) error {
+	var rate *domain.Decimal
+	if raw := strings.TrimSpace(fields.Get("exchange_rate")); raw != "" {
+		r, err := domain.ParseDecimal(strings.Replace(raw, ",", ".", 1))
+		if err != nil {
+			return conversionError("exchange_rate", fields["exchange_rate"], err)
+		}
+		if r.IsZero() || r.IsNegative() {
+			return conversionError("exchange_rate", fields["exchange_rate"], errors.New("not a positive rate"))
+		}
+		rate = &r
+	}
+
+	switch {
+	case fields.Get("foreign_amount") != "":
+		foreign, currency, err := ParseMoney(fields.Get("foreign_amount"), "")
+		if err != nil {
+			return conversionError("foreign_amount", fields["foreign_amount"], err)
+		}
+		if currency == "" {
+			return conversionError("foreign_amount", fields["foreign_amount"], errors.New("no currency"))
+		}
+		if currency == txn.TxCurrency {
+			return nil // not foreign after all
+		}
+		foreign = foreign.Abs()
+		txn.ForeignAmount, txn.ForeignCurrency = &foreign, &currency
+		if rate == nil {
+			if r, err := txn.TxAmount.Div(foreign, 6); err == nil {
+				rate = &r
+			}
+		}
+
+	case rate != nil && txn.TxCurrency != home:
+		// the email only gives the amount in the foreign currency, the account is
+		// charged its conversion
+		foreign, currency := txn.TxAmount, txn.TxCurrency
+		converted, err := foreign.Mul(*rate, 2)
+		if err != nil {
+			return conversionError("exchange_rate", fields["exchange_rate"], err)
+		}
+		txn.TxAmount, txn.TxCurrency = converted, home
+		txn.ForeignAmount, txn.ForeignCurrency = &foreign, &currency
+
+	case txn.TxCurrency != home:
+		// nothing to convert with. the amount is kept as the email gives it and marked
+		// foreign, the account is still kept in home
+		foreign, currency := txn.TxAmount, txn.TxCurrency
+		txn.ForeignAmount, txn.ForeignCurrency = &foreign, &currency
+		src := txn.Provenance["amount"]
+		src.Note = "not converted, no exchange rate"
+		txn.Provenance["amount"] = src
+
+	default:
+		return nil
+	}
+
+	txn.ExchangeRate = rate
+	return nil
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// reverse returns the opposite direction
+func reverse(dir domain.Direction) domain.Direction {
+	if dir == domain.In {
+		return domain.Out
+	}
+	return domain.In
+}
+
+// conversionError reports a field that was found but could not be converted
+func conversionError(key string, field Field, err error) *ParseError {
+	return &ParseError{Field: key, Pattern: field.Pattern, Value: field.Value, Err: err}
+}
+
+// parseMetaDate parses the Date of an EmailMeta or its Envelope
+func parseMetaDate(raw string) (time.Time, bool) {
+	if raw == "" {
+		return time.Time{}, false
+	}
+	if parsed, err := time.Parse(time.RFC3339, raw); err == nil {
+		return parsed, true
+	}
+	if parsed, err := time.Parse("Mon, 2 Jan 2006 15:04:05 -0700", raw); err == nil {
+		return parsed, true
+	}
+	return time.Time{}, false
 }
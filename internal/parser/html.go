@@ -0,0 +1,161 @@
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"null-email-parser/internal/email"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// SelectText returns the normalized text of the first element matching a CSS selector
+func SelectText(doc *html.Node, selector string) (string, error) {
+	if doc == nil {
+		return "", fmt.Errorf("email has no html body")
+	}
+
+	sel, err := cascadia.Compile(selector)
+	if err != nil {
+		return "", fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+
+	n := sel.MatchFirst(doc)
+	if n == nil {
+		return "", fmt.Errorf("selector %q matched nothing", selector)
+	}
+
+	return email.NodeText(n), nil
+}
+
+// SelectAllText returns the normalized text of every element matching a CSS selector
+func SelectAllText(doc *html.Node, selector string) ([]string, error) {
+	if doc == nil {
+		return nil, fmt.Errorf("email has no html body")
+	}
+
+	sel, err := cascadia.Compile(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+
+	nodes := sel.MatchAll(doc)
+	out := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, email.NodeText(n))
+	}
+
+	return out, nil
+}
+
+// LabelValue finds the element whose text is label (e.g. "Purchase Amount:") and returns
+// the value shown next to it: the next non-empty table cell when the label is in a
+// table, otherwise the next text in the document. matching ignores case and a trailing colon
+func LabelValue(doc *html.Node, label string) (string, bool) {
+	if doc == nil {
+		return "", false
+	}
+
+	labelNode := findLabel(doc, normalizeLabel(label))
+	if labelNode == nil {
+		return "", false
+	}
+
+	if cell := ancestor(labelNode, atom.Td, atom.Th); cell != nil {
+		for sib := cell.NextSibling; sib != nil; sib = sib.NextSibling {
+			if sib.Type != html.ElementNode {
+				continue
+			}
+			if text := email.NodeText(sib); text != "" {
+				return text, true
+			}
+		}
+	}
+
+	for n := nextInDocument(labelNode); n != nil; n = nextInDocument(n) {
+		if n.Type != html.TextNode || isHidden(n) {
+			continue
+		}
+		if text := email.Normalize(n.Data); text != "" {
+			return text, true
+		}
+	}
+
+	return "", false
+}
+
+// ExtractHTMLFields looks up each label with LabelValue and returns the values by key.
+// the fields ExtractFields treats as optional are optional here too
+func ExtractHTMLFields(doc *html.Node, labels map[string]string) (Fields, error) {
+	out := make(Fields, len(labels))
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		label := labels[key]
+		value, ok := LabelValue(doc, label)
+		if !ok {
+			if optionalFields[key] {
+				out[key] = Field{Pattern: label, Offset: -1}
+				continue
+			}
+			return nil, &ParseError{Field: key, Pattern: label, Err: fmt.Errorf("label not found in html")}
+		}
+		out[key] = Field{Value: value, Pattern: label, Offset: -1}
+	}
+
+	return out, nil
+}
+
+func normalizeLabel(s string) string {
+	s = strings.ToLower(email.Normalize(s))
+	return strings.TrimSpace(strings.TrimSuffix(s, ":"))
+}
+
+// findLabel returns the deepest element whose whole text is the label
+func findLabel(n *html.Node, label string) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findLabel(c, label); found != nil {
+			return found
+		}
+	}
+
+	if n.Type == html.ElementNode && normalizeLabel(email.NodeText(n)) == label {
+		return n
+	}
+
+	return nil
+}
+
+func ancestor(n *html.Node, atoms ...atom.Atom) *html.Node {
+	for p := n; p != nil; p = p.Parent {
+		for _, a := range atoms {
+			if p.Type == html.ElementNode && p.DataAtom == a {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// nextInDocument returns the node after n in document order, skipping n's children
+func nextInDocument(n *html.Node) *html.Node {
+	for ; n != nil; n = n.Parent {
+		if n.NextSibling != nil {
+			return firstLeaf(n.NextSibling)
+		}
+	}
+	return nil
+}
+
+func firstLeaf(n *html.Node) *html.Node {
+	for n.FirstChild != nil {
+		n = n.FirstChild
+	}
+	return n
+}
+
+func isHidden(n *html.Node) bool {
+	return ancestor(n, atom.Script, atom.Style, atom.Head, atom.Title) != nil
+}
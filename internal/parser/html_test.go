@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const alertHTML = `<html><head><title>Account:</title></head><body>
+<div class="intro">A purchase was made.</div>
+<table>
+  <tr>
+    <td><p><strong>Account:</strong></p></td>
+    <td width="30"></td>
+    <td><p>************1001</p></td>
+  </tr>
+  <tr>
+    <td><p><strong>Purchase Amount:</strong></p></td>
+    <td width="30"></td>
+    <td><p> $1,234.56 </p></td>
+  </tr>
+</table>
+<p><b>Merchant</b></p>
+<p>TIM&nbsp;HORTONS #0000</p>
+<ul><li class="item">one</li><li class="item">two</li></ul>
+</body></html>`
+
+func parseAlert(t *testing.T) *html.Node {
+	t.Helper()
+	doc, err := html.Parse(strings.NewReader(alertHTML))
+	if err != nil {
+		t.Fatalf("html.Parse: %v", err)
+	}
+	return doc
+}
+
+func TestLabelValue(t *testing.T) {
+	doc := parseAlert(t)
+
+	tests := []struct {
+		label string
+		want  string
+		found bool
+	}{
+		{"Account:", "************1001", true},
+		{"purchase amount", "$1,234.56", true},
+		{"Merchant:", "TIM HORTONS #0000", true},
+		{"Transaction Date:", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := LabelValue(doc, tt.label)
+		if ok != tt.found || got != tt.want {
+			t.Errorf("LabelValue(%q) = %q, %v; want %q, %v", tt.label, got, ok, tt.want, tt.found)
+		}
+	}
+}
+
+func TestSelectText(t *testing.T) {
+	doc := parseAlert(t)
+
+	got, err := SelectText(doc, "div.intro")
+	if err != nil || got != "A purchase was made." {
+		t.Errorf("SelectText(div.intro) = %q, %v", got, err)
+	}
+
+	all, err := SelectAllText(doc, "li.item")
+	if err != nil || strings.Join(all, ",") != "one,two" {
+		t.Errorf("SelectAllText(li.item) = %q, %v", all, err)
+	}
+
+	if _, err := SelectText(doc, "span.missing"); err == nil {
+		t.Error("SelectText with no match should return an error")
+	}
+	if _, err := SelectText(doc, "td[["); err == nil {
+		t.Error("SelectText with an invalid selector should return an error")
+	}
+	if _, err := SelectText(nil, "p"); err == nil {
+		t.Error("SelectText on a text-only email should return an error")
+	}
+}
+
+func TestExtractHTMLFields(t *testing.T) {
+	doc := parseAlert(t)
+
+	fields, err := ExtractHTMLFields(doc, map[string]string{
+		"amount":  "Purchase Amount:",
+		"account": "Card Number:",
+	})
+	if err != nil {
+		t.Fatalf("ExtractHTMLFields: %v", err)
+	}
+	if fields.Get("amount") != "$1,234.56" || fields.Get("account") != "" {
+		t.Errorf("fields = %v", fields)
+	}
+
+	// the same fields as with ExtractFields may be missing
+	for key := range optionalFields {
+		if _, err := ExtractHTMLFields(doc, map[string]string{key: "Not In The Alert:"}); err != nil {
+			t.Errorf("missing optional %s: %v", key, err)
+		}
+	}
+
+	if _, err := ExtractHTMLFields(doc, map[string]string{"txdate": "Transaction Date:"}); err == nil {
+		t.Error("missing required label should return an error")
+	}
+}
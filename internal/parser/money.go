@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"null-email-parser/internal/domain"
+)
+
+// currencySymbols maps the currency symbols of amounts to ISO 4217 codes. "$" alone
+// is ambiguous and takes the fallback currency of the parser
+var currencySymbols = map[string]string{
+	"CA$": "CAD",
+	"C$":  "CAD",
+	"US$": "USD",
+	"U$":  "USD",
+	"A$":  "AUD",
+	"€":   "EUR",
+	"£":   "GBP",
+	"$":   "",
+}
+
+const (
+	moneySymbol = `(?:CA\$|C\$|US\$|U\$|A\$|\$|€|£)`
+	moneyCode   = `(?:CAD|USD|EUR|GBP|AUD|MXN|JPY|CHF)`
+	// the fraction takes every digit, so "$1.775" is not read as "$1.77"
+	moneyFraction = `(?:[.,]\d+)?`
+	moneyNumber   = `(?:\d{1,3}(?:,\d{3})+` + moneyFraction + `|\d+` + moneyFraction + `)`
+	// spaces group thousands only before the currency, "1 234,56 $": after one,
+	// "$5 123 Main St" would read an address as part of the amount
+	moneySpaced = `(?:\d{1,3}(?:[ \x{a0}\x{202f}]\d{3})+` + moneyFraction + `|` + moneyNumber + `)`
+)
+
+// MoneyPattern matches an amount with its currency: "$1,234.56", "1 234,56 $",
+// "CA$12", "US$ 5.00", "USD 12.00", "12.00 EUR", "-$5.00" or "($5.00)". it has no
+// capture group, wrap it in one for ExtractFields, e.g. "Amount: (" + MoneyPattern + ")"
+const MoneyPattern = `(?:[-−]\s?|\(\s?)?` +
+	`(?:(?:` + moneyCode + `\s?|` + moneySymbol + `\s?)[-−]?` + moneyNumber + `\b` +
+	`|` + moneySpaced + `\s?(?:` + moneySymbol + `|` + moneyCode + `\b))` +
+	`\)?`
+
+var moneyMarkerRe = regexp.MustCompile(`^(` + moneyCode + `|` + moneySymbol + `)|(` + moneySymbol + `|` + moneyCode + `)$`)
+
+// ParseMoney reads an amount written with or without a currency, as matched by
+// MoneyPattern or a bare number such as "1,234.56". it returns the amount, negative
+// for "-$5.00" and "($5.00)", and the ISO code of its currency, fallback when the
+// amount has none or just "$"
+func ParseMoney(s, fallback string) (domain.Decimal, string, error) {
+	raw := s
+	s = strings.TrimSpace(s)
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative, s = true, strings.TrimSpace(s[1:len(s)-1])
+	}
+	if rest, ok := cutSign(s); ok {
+		negative, s = !negative, rest
+	}
+
+	currency := fallback
+	if m := moneyMarkerRe.FindStringSubmatchIndex(s); m != nil {
+		marker := s[m[0]:m[1]]
+		if code, ok := currencySymbols[marker]; ok {
+			if code != "" {
+				currency = code
+			}
+		} else {
+			currency = marker
+		}
+		s = strings.TrimSpace(s[:m[0]] + s[m[1]:])
+	}
+	if rest, ok := cutSign(s); ok {
+		negative, s = !negative, rest
+	}
+
+	number, err := normalizeNumber(s)
+	if err != nil {
+		return domain.Decimal{}, "", fmt.Errorf("invalid amount %q: %w", raw, err)
+	}
+	amount, err := domain.ParseDecimal(number)
+	if err != nil {
+		return domain.Decimal{}, "", fmt.Errorf("invalid amount %q: %w", raw, err)
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	return amount, currency, nil
+}
+
+// cutSign removes a leading minus sign
+func cutSign(s string) (string, bool) {
+	for _, sign := range []string{"-", "−"} {
+		if rest, ok := strings.CutPrefix(s, sign); ok {
+			return strings.TrimSpace(rest), true
+		}
+	}
+	return s, false
+}
+
+var errNotANumber = errors.New("not a number")
+
+// normalizeNumber turns "1,234.56", "1 234,56" or "1.234.567" into "1234.56" style.
+// the last separator is the decimal one when one or two digits follow it, or when it
+// is a lone dot
+func normalizeNumber(s string) (string, error) {
+	s = strings.NewReplacer("\u00a0", " ", "\u202f", " ").Replace(s)
+	if s == "" || strings.Trim(s, "0123456789., ") != "" || s[0] < '0' || s[0] > '9' {
+		return "", errNotANumber
+	}
+
+	last := strings.LastIndexAny(s, ".,")
+	decimal := false
+	if last >= 0 {
+		digits := len(s) - last - 1
+		sep := s[last]
+		switch {
+		case strings.Count(s, string(sep)) > 1:
+			// repeated, a thousands separator
+		case digits == 1 || digits == 2:
+			decimal = true
+		case sep == '.' && !strings.Contains(s, ","):
+			decimal = true
+		}
+	}
+
+	whole, fraction := s, ""
+	if decimal {
+		whole, fraction = s[:last], s[last+1:]
+	}
+	if strings.ContainsAny(fraction, "., ") {
+		return "", errNotANumber
+	}
+
+	// thousands groups have three digits
+	groups := strings.FieldsFunc(whole, func(r rune) bool { return r == ',' || r == '.' || r == ' ' })
+	if len(groups) == 0 {
+		return "", errNotANumber
+	}
+	for i, g := range groups {
+		if i > 0 && len(g) != 3 || i == 0 && len(groups) > 1 && len(g) > 3 {
+			return "", errNotANumber
+		}
+	}
+	if len(groups)-1 != len(whole)-len(strings.Join(groups, "")) {
+		return "", errNotANumber // doubled separators
+	}
+
+	number := strings.Join(groups, "")
+	if fraction != "" {
+		number += "." + fraction
+	}
+	return number, nil
+}
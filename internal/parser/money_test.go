@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"regexp"
+	"testing"
+
+	"null-email-parser/internal/domain"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in       string
+		amount   string
+		currency string
+	}{
+		{"$1,234.56", "1234.56", "CAD"},
+		{"1 234,56 $", "1234.56", "CAD"},
+		{"1 234,56 $", "1234.56", "CAD"},
+		{"CA$12", "12.00", "CAD"},
+		{"C$ 7.5", "7.50", "CAD"},
+		{"US$5.00", "5.00", "USD"},
+		{"USD 12.00", "12.00", "USD"},
+		{"12.00 EUR", "12.00", "EUR"},
+		{"€3,50", "3.50", "EUR"},
+		{"£1,000", "1000.00", "GBP"},
+		{"-$5.00", "-5.00", "CAD"},
+		{"$-5.00", "-5.00", "CAD"},
+		{"($5.00)", "-5.00", "CAD"},
+		{"1,234,567.89", "1234567.89", "CAD"},
+		{"1.234.567,89", "1234567.89", "CAD"},
+		{"1234.5", "1234.50", "CAD"},
+		{"39.50", "39.50", "CAD"},
+	}
+	for _, tt := range tests {
+		amount, currency, err := ParseMoney(tt.in, "CAD")
+		if err != nil || amount.String() != tt.amount || currency != tt.currency {
+			t.Errorf("ParseMoney(%q) = %v, %q, %v; want %s, %q", tt.in, amount, currency, err, tt.amount, tt.currency)
+		}
+	}
+
+	for _, bad := range []string{"", "$", "1.2.3", "12,34,56", "1,,234", "abc", "$12 apples"} {
+		if amount, _, err := ParseMoney(bad, "CAD"); err == nil {
+			t.Errorf("ParseMoney(%q) = %v; want an error", bad, amount)
+		}
+	}
+}
+
+func TestMoneyPattern(t *testing.T) {
+	re := regexp.MustCompile(`(` + MoneyPattern + `)`)
+	tests := map[string]string{
+		"a purchase of $1,234.56 was made":      "$1,234.56",
+		"un achat de 1 234,56 $ a été effectué": "1 234,56 $",
+		"charged US$ 5.00 at":                   "US$ 5.00",
+		"refund of ($5.00) issued":              "($5.00)",
+		"amount: USD 12.00.":                    "USD 12.00",
+		"call 1-800-769-2512 for $1.77":         "$1.77",
+		"a purchase of $1.775 was made":         "$1.775",
+		"paid $5 123 Main St":                   "$5",
+		"paid $5 1234 apples":                   "$5",
+		"a fee of 2 500 $ applies":              "2 500 $",
+		"at 5 123 Main St":                      "",
+	}
+	for text, want := range tests {
+		if got := re.FindString(text); got != want {
+			t.Errorf("MoneyPattern in %q = %q; want %q", text, got, want)
+		}
+	}
+}
+
+func TestBuildTransactionMoney(t *testing.T) {
+	fields := Fields{
+		"amount": {Value: "(US$1,250.00)"},
+		"txdate": {Value: "September 13, 2025"},
+	}
+	txn, err := BuildTransaction(EmailMeta{}, fields, "bank", "CAD", "out", "refund")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txn.TxAmount != domain.MustDecimal("1250") || txn.TxCurrency != "USD" || txn.TxDirection != "in" {
+		t.Errorf("txn = %v %s %s; want a 1250 USD refund, money in", txn.TxAmount, txn.TxCurrency, txn.TxDirection)
+	}
+}
+
+func TestBuildTransactionForeign(t *testing.T) {
+	tests := []struct {
+		name                                  string
+		amount, foreign, rate                 string
+		wantAmount, wantCurrency              string
+		wantForeign, wantForeignCur, wantRate string
+	}{
+		{name: "converted amount and rate", amount: "CAD 16.52", foreign: "USD 12.00", rate: "1.3767",
+			wantAmount: "16.52", wantCurrency: "CAD", wantForeign: "12.00", wantForeignCur: "USD", wantRate: "1.3767"},
+		{name: "rate computed", amount: "$16.52", foreign: "12.00 EUR",
+			wantAmount: "16.52", wantCurrency: "CAD", wantForeign: "12.00", wantForeignCur: "EUR", wantRate: "1.376667"},
+		{name: "converted with the rate", amount: "USD 12.00", rate: "1,3767",
+			wantAmount: "16.52", wantCurrency: "CAD", wantForeign: "12.00", wantForeignCur: "USD", wantRate: "1.3767"},
+		{name: "no rate", amount: "USD 12.00",
+			wantAmount: "12.00", wantCurrency: "USD", wantForeign: "12.00", wantForeignCur: "USD"},
+		{name: "same currency", amount: "$12.00", foreign: "CA$12.00", rate: "1",
+			wantAmount: "12.00", wantCurrency: "CAD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := Fields{
+				"amount":         {Value: tt.amount},
+				"txdate":         {Value: "September 13, 2025"},
+				"foreign_amount": {Value: tt.foreign},
+				"exchange_rate":  {Value: tt.rate},
+			}
+			txn, err := BuildTransaction(EmailMeta{}, fields, "bank", "CAD", "out", "desc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if txn.TxAmount.String() != tt.wantAmount || txn.TxCurrency != tt.wantCurrency {
+				t.Errorf("amount = %s %s; want %s %s", txn.TxAmount, txn.TxCurrency, tt.wantAmount, tt.wantCurrency)
+			}
+			if txn.AccountCurrency != "CAD" {
+				t.Errorf("account currency = %q; want the parser's CAD", txn.AccountCurrency)
+			}
+
+			var foreign, currency, rate string
+			if txn.ForeignAmount != nil {
+				foreign, currency = txn.ForeignAmount.String(), *txn.ForeignCurrency
+			}
+			if txn.ExchangeRate != nil {
+				rate = txn.ExchangeRate.String()
+			}
+			if foreign != tt.wantForeign || currency != tt.wantForeignCur || rate != tt.wantRate {
+				t.Errorf("foreign = %q %q at %q; want %q %q at %q", foreign, currency, rate, tt.wantForeign, tt.wantForeignCur, tt.wantRate)
+			}
+		})
+	}
+
+	for _, bad := range []Fields{
+		{"foreign_amount": {Value: "12.00"}},
+		{"exchange_rate": {Value: "-1"}},
+		{"exchange_rate": {Value: "about 1.37"}},
+	} {
+		bad["amount"] = Field{Value: "USD 12.00"}
+		bad["txdate"] = Field{Value: "September 13, 2025"}
+		if _, err := BuildTransaction(EmailMeta{}, bad, "bank", "CAD", "out", "desc"); err == nil {
+			t.Errorf("BuildTransaction(%v) did not fail", bad)
+		}
+	}
+}
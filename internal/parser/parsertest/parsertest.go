@@ -0,0 +1,164 @@
+// Package parsertest checks parsers against golden files. every testdata/*.decoded.eml
+// fixture has a sidecar *.expected.json holding the parser the registry picks for it
+// and the transactions it returns. after an intentional change, regenerate them with
+//
+//	go test ./internal/email/rbc -run TestGolden -update
+package parsertest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"null-email-parser/internal/domain"
+	"null-email-parser/internal/fixture"
+	"null-email-parser/internal/parser"
+)
+
+var update = flag.Bool("update", false, "rewrite the *.expected.json golden files")
+
+// Run runs every fixture in dir through the registry and compares the result with
+// its golden file. the parsers under test must be registered, e.g. by importing
+// internal/email/all
+func Run(t *testing.T, dir string) {
+	t.Helper()
+
+	fixtures, err := filepath.Glob(filepath.Join(dir, "*"+fixture.Suffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatalf("no *%s fixtures in %s", fixture.Suffix, dir)
+	}
+
+	for _, path := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(path), fixture.Suffix)
+		t.Run(name, func(t *testing.T) { check(t, path) })
+	}
+}
+
+func check(t *testing.T, path string) {
+	got, err := Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotJSON, err := fixture.MarshalExpected(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := fixture.GoldenPath(path)
+	if *update {
+		if err := fixture.WriteExpected(path, got); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	wantJSON, err := os.ReadFile(golden)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("%s is missing, run the test with -update to create it", golden)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(gotJSON), bytes.TrimSpace(wantJSON)) {
+		t.Errorf("result differs from %s, run with -update if the change is intended\n%s",
+			golden, diff(string(wantJSON), string(gotJSON)))
+	}
+}
+
+// LoadMeta reads an email file as the handler would receive it, failing the test
+// when it can't
+func LoadMeta(t testing.TB, path string) parser.EmailMeta {
+	t.Helper()
+
+	meta, err := parser.ReadEmailMeta(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return meta
+}
+
+// Parse runs a fixture through the registry the way the email handler does
+func Parse(path string) (fixture.Expected, error) {
+	meta, err := parser.ReadEmailMeta(path)
+	if err != nil {
+		return fixture.Expected{}, err
+	}
+
+	p, err := parser.Lookup(meta)
+	if err != nil {
+		return fixture.Expected{}, fmt.Errorf("%s: %w", path, err) // ambiguity is always a bug in a fixture test
+	}
+
+	out := fixture.Expected{Transactions: []fixture.Transaction{}}
+	if p == nil {
+		return out, nil
+	}
+	out.Parser = parser.Name(p)
+
+	txns, err := p.ParseAll(meta)
+	if err != nil {
+		out.Error = err.Error()
+		return out, nil
+	}
+	for _, txn := range txns {
+		out.Transactions = append(out.Transactions, fromDomain(txn))
+	}
+	return out, nil
+}
+
+func fromDomain(txn *domain.Transaction) fixture.Transaction {
+	return fixture.Transaction{
+		Date:            txn.TxDate.Format(time.RFC3339),
+		Bank:            txn.TxBank,
+		Account:         txn.TxAccount,
+		AccountKind:     string(txn.AccountKind),
+		Amount:          txn.TxAmount.String(),
+		Currency:        txn.TxCurrency,
+		Direction:       string(txn.TxDirection),
+		Description:     txn.TxDesc,
+		Category:        txn.Category,
+		Merchant:        txn.Merchant,
+		ForeignAmount:   decimalString(txn.ForeignAmount),
+		ForeignCurrency: txn.ForeignCurrency,
+		ExchangeRate:    decimalString(txn.ExchangeRate),
+	}
+}
+
+func decimalString(d *domain.Decimal) *string {
+	if d == nil {
+		return nil
+	}
+	s := d.String()
+	return &s
+}
+
+// diff lists the lines that differ between two golden files
+func diff(want, got string) string {
+	wantLines := strings.Split(strings.TrimSpace(want), "\n")
+	gotLines := strings.Split(strings.TrimSpace(got), "\n")
+
+	var b strings.Builder
+	for i := range max(len(wantLines), len(gotLines)) {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			fmt.Fprintf(&b, "line %d:\n- %s\n+ %s\n", i+1, strings.TrimSpace(w), strings.TrimSpace(g))
+		}
+	}
+	return b.String()
+}
@@ -1,16 +1,224 @@
 package parser
 
-var registry []Parser
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Info identifies a registered parser
+type Info struct {
+	ID       string   // unique name, e.g. "rbc.purchase"
+	Bank     string   // e.g. "rbc"
+	Version  int      // bumped when the parser is changed for a new email template
+	Priority int      // when several parsers match an email, the highest priority wins
+	Domains  []string // sender domains handled, subdomains included. empty for any sender
+}
+
+// Describer is implemented by parsers that provide their own Info. parsers without it
+// are named with Name, belong to the bank of their package and have priority 0
+type Describer interface {
+	Describe() Info
+}
+
+// Describe returns the Info of a parser, filling in what it does not provide
+func Describe(p any) Info {
+	if s, ok := p.(single); ok {
+		p = s.Parser
+	}
+
+	var info Info
+	if d, ok := p.(Describer); ok {
+		info = d.Describe()
+	}
+	if info.ID == "" {
+		info.ID = Name(p)
+	}
+	if info.Bank == "" {
+		info.Bank, _, _ = strings.Cut(info.ID, ".")
+	}
+	if info.Version == 0 {
+		info.Version = 1
+	}
+
+	domains := make([]string, 0, len(info.Domains))
+	for _, d := range info.Domains {
+		if d = normalizeDomain(d); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	info.Domains = domains
+
+	return info
+}
+
+func normalizeDomain(d string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(d), "@."))
+}
+
+// entry is a registered parser
+type entry struct {
+	info   Info
+	parser MultiParser
+	order  int // position in find order, see reindex
+}
+
+var (
+	mu      sync.RWMutex
+	builtin []*entry // compiled into the binary
+
+	// sets are groups of parsers replaced as a whole, e.g. when a directory is reloaded
+	sets = map[string][]*entry{}
+
+	// ordered holds every entry in find order, byDomain and anyDomain index it
+	ordered   []*entry
+	byDomain  map[string][]*entry
+	anyDomain []*entry
+)
 
 // Register adds a new parser to the registry
-func Register(p Parser) { registry = append(registry, p) }
+func Register(p Parser) { RegisterMulti(AsMulti(p)) }
+
+// RegisterMulti adds a parser that can return several transactions per email. it
+// panics if the parser id is already taken, like registering a duplicate flag
+func RegisterMulti(p MultiParser) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	e := &entry{info: Describe(p), parser: p}
+	for _, other := range builtin {
+		if other.info.ID == e.info.ID {
+			panic(fmt.Sprintf("parser: %q registered twice", e.info.ID))
+		}
+	}
+
+	builtin = append(builtin, e)
+	reindex()
+}
 
-// Find returns the first parser that matches the given EmailMeta
-func Find(meta EmailMeta) Parser {
-	for _, p := range registry {
-		if p.Match(meta) {
-			return p
+// RegisterSet replaces the parsers previously registered under name. an empty
+// list removes the set
+func RegisterSet(name string, parsers []MultiParser) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(parsers) == 0 {
+		delete(sets, name)
+	} else {
+		entries := make([]*entry, len(parsers))
+		for i, p := range parsers {
+			entries[i] = &entry{info: Describe(p), parser: p}
 		}
+		sets[name] = entries
+	}
+	reindex()
+}
+
+// reindex orders every entry by priority, then compiled in parsers before sets (sets
+// by name), then id, and rebuilds the domain index. callers hold mu
+func reindex() {
+	byID := func(a, b *entry) int { return strings.Compare(a.info.ID, b.info.ID) }
+
+	all := slices.SortedFunc(slices.Values(builtin), byID)
+	for _, name := range slices.Sorted(maps.Keys(sets)) {
+		all = append(all, slices.SortedFunc(slices.Values(sets[name]), byID)...)
+	}
+
+	slices.SortStableFunc(all, func(a, b *entry) int {
+		if a.info.Priority != b.info.Priority {
+			return b.info.Priority - a.info.Priority
+		}
+		return 0
+	})
+
+	ordered, byDomain, anyDomain = all, map[string][]*entry{}, nil
+	for i, e := range all {
+		e.order = i
+		if len(e.info.Domains) == 0 {
+			anyDomain = append(anyDomain, e)
+			continue
+		}
+		for _, d := range e.info.Domains {
+			byDomain[d] = append(byDomain[d], e)
+		}
+	}
+}
+
+// candidates returns the entries that may handle an email from domain, in find order
+func candidates(domain string) []*entry {
+	out := slices.Clone(anyDomain)
+	for d := domain; d != ""; {
+		out = append(out, byDomain[d]...)
+		_, parent, ok := strings.Cut(d, ".")
+		if !ok {
+			break
+		}
+		d = parent
+	}
+
+	slices.SortFunc(out, func(a, b *entry) int { return a.order - b.order })
+	return slices.CompactFunc(out, func(a, b *entry) bool { return a == b })
+}
+
+// AmbiguousError reports an email matched by several parsers of the same priority
+type AmbiguousError struct {
+	IDs      []string // the matching parsers, the one used first
+	Priority int
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("email matches %d parsers with priority %d, using %s: %s",
+		len(e.IDs), e.Priority, e.IDs[0], strings.Join(e.IDs, ", "))
+}
+
+// Lookup returns the parser for an email: among the matching parsers the one with the
+// highest priority, with ties going to compiled in parsers, then sets by name. when
+// several parsers of that priority match, the chosen one is returned along with an
+// *AmbiguousError. only parsers for the sender's domain or for any sender are tried
+func Lookup(meta EmailMeta) (MultiParser, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var found []*entry
+	for _, e := range candidates(meta.SenderDomain()) {
+		if len(found) > 0 && e.info.Priority < found[0].info.Priority {
+			break // lower priorities cannot change the result
+		}
+		if e.parser.Match(meta) {
+			found = append(found, e)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, nil
+	case 1:
+		return found[0].parser, nil
+	}
+
+	ids := make([]string, len(found))
+	for i, e := range found {
+		ids[i] = e.info.ID
+	}
+	return found[0].parser, &AmbiguousError{IDs: ids, Priority: found[0].info.Priority}
+}
+
+// Find returns the parser for an email like Lookup, ignoring ambiguity
+func Find(meta EmailMeta) MultiParser {
+	p, _ := Lookup(meta)
+	return p
+}
+
+// Registered returns the Info of every registered parser in the order they are tried
+func Registered() []Info {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	out := make([]Info, len(ordered))
+	for i, e := range ordered {
+		out[i] = e.info
 	}
-	return nil
+	return out
 }
@@ -0,0 +1,212 @@
+package parser
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"null-email-parser/internal/domain"
+)
+
+type fakeParser struct {
+	subject string
+	txn     *domain.Transaction
+	err     error
+}
+
+func (f fakeParser) Match(m EmailMeta) bool { return strings.Contains(m.Subject, f.subject) }
+
+func (f fakeParser) Parse(EmailMeta) (*domain.Transaction, error) { return f.txn, f.err }
+
+type transferParser struct{}
+
+func (transferParser) Match(m EmailMeta) bool { return strings.Contains(m.Subject, "You moved money") }
+
+func (transferParser) ParseAll(m EmailMeta) ([]*domain.Transaction, error) {
+	return []*domain.Transaction{
+		{EmailID: m.ID, TxAccount: "Chequing", TxAmount: domain.MustDecimal("50"), TxDirection: domain.Out},
+		{EmailID: m.ID, TxAccount: "Savings", TxAmount: domain.MustDecimal("50"), TxDirection: domain.In},
+	}, nil
+}
+
+// described is a fakeParser with an Info
+type described struct {
+	fakeParser
+	info Info
+}
+
+func (d described) Describe() Info { return d.info }
+
+// withRegistry runs a test against an empty registry
+func withRegistry(t *testing.T) {
+	t.Helper()
+
+	mu.Lock()
+	saved, savedSets := builtin, sets
+	builtin, sets = nil, map[string][]*entry{}
+	reindex()
+	mu.Unlock()
+
+	t.Cleanup(func() {
+		mu.Lock()
+		builtin, sets = saved, savedSets
+		reindex()
+		mu.Unlock()
+	})
+}
+
+func TestAsMulti(t *testing.T) {
+	txn := &domain.Transaction{TxAmount: domain.MustDecimal("1")}
+
+	got, err := AsMulti(fakeParser{txn: txn}).ParseAll(EmailMeta{})
+	if err != nil || len(got) != 1 || got[0] != txn {
+		t.Errorf("ParseAll = %v, %v; want the single transaction", got, err)
+	}
+
+	got, err = AsMulti(fakeParser{}).ParseAll(EmailMeta{})
+	if err != nil || len(got) != 0 {
+		t.Errorf("ParseAll with nil transaction = %v, %v; want none", got, err)
+	}
+
+	wantErr := errors.New("boom")
+	if _, err := AsMulti(fakeParser{err: wantErr}).ParseAll(EmailMeta{}); !errors.Is(err, wantErr) {
+		t.Errorf("ParseAll error = %v; want %v", err, wantErr)
+	}
+}
+
+func TestFindMultiParser(t *testing.T) {
+	withRegistry(t)
+	Register(fakeParser{subject: "You made a purchase"})
+	RegisterMulti(transferParser{})
+
+	p := Find(EmailMeta{ID: "x", Subject: "You moved money"})
+	if p == nil {
+		t.Fatal("Find returned nil")
+	}
+
+	txns, err := p.ParseAll(EmailMeta{ID: "x", Subject: "You moved money"})
+	if err != nil {
+		t.Fatalf("ParseAll returned error: %v", err)
+	}
+	if len(txns) != 2 || txns[0].TxDirection != domain.Out || txns[1].TxDirection != domain.In {
+		t.Errorf("ParseAll = %+v; want both legs of the transfer", txns)
+	}
+
+	if Find(EmailMeta{Subject: "Newsletter"}) != nil {
+		t.Error("Find matched an unrelated email")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	info := Describe(AsMulti(fakeParser{}))
+	if info.ID != "parser.fakeParser" || info.Bank != "parser" || info.Version != 1 || info.Priority != 0 {
+		t.Errorf("Describe = %+v; want defaults derived from the type", info)
+	}
+
+	info = Describe(AsMulti(described{info: Info{ID: "mybank.card", Domains: []string{"@Alerts.MyBank.com", " "}}}))
+	if info.Bank != "mybank" || len(info.Domains) != 1 || info.Domains[0] != "alerts.mybank.com" {
+		t.Errorf("Describe = %+v; want the bank from the id and normalized domains", info)
+	}
+	if name := Name(AsMulti(described{info: Info{ID: "mybank.card"}})); name != "mybank.card" {
+		t.Errorf("Name = %q; want the described id", name)
+	}
+}
+
+func TestLookupPriority(t *testing.T) {
+	withRegistry(t)
+	Register(described{fakeParser{subject: "purchase"}, Info{ID: "generic.purchase"}})
+	RegisterSet("test", []MultiParser{
+		AsMulti(described{fakeParser{subject: "purchase"}, Info{ID: "mybank.purchase", Priority: 10}}),
+	})
+
+	p, err := Lookup(EmailMeta{Subject: "You made a purchase"})
+	if err != nil || Name(p) != "mybank.purchase" {
+		t.Errorf("Lookup = %s, %v; want the higher priority parser without ambiguity", Name(p), err)
+	}
+}
+
+func TestLookupAmbiguous(t *testing.T) {
+	withRegistry(t)
+	RegisterSet("b", []MultiParser{AsMulti(described{fakeParser{subject: "purchase"}, Info{ID: "a.purchase"}})})
+	RegisterSet("a", []MultiParser{AsMulti(described{fakeParser{subject: "purchase"}, Info{ID: "z.purchase"}})})
+	Register(described{fakeParser{subject: "purchase"}, Info{ID: "m.purchase"}})
+
+	for range 3 { // the choice must not depend on map or registration order
+		p, err := Lookup(EmailMeta{Subject: "purchase"})
+
+		var amb *AmbiguousError
+		if !errors.As(err, &amb) {
+			t.Fatalf("err = %v; want an AmbiguousError", err)
+		}
+		if Name(p) != "m.purchase" || strings.Join(amb.IDs, " ") != "m.purchase z.purchase a.purchase" {
+			t.Errorf("Lookup = %s, %v; want the compiled in parser, then sets by name", Name(p), amb.IDs)
+		}
+	}
+}
+
+func TestLookupDomainIndex(t *testing.T) {
+	withRegistry(t)
+
+	tried := map[string]int{}
+	forDomain := func(id string, domains ...string) Parser {
+		return described{fakeParser{subject: "purchase"}, Info{ID: id, Domains: domains}}
+	}
+	for _, p := range []Parser{
+		forDomain("mybank.purchase", "mybank.com"),
+		forDomain("otherbank.purchase", "otherbank.com"),
+	} {
+		Register(countingParser{Parser: p, tried: tried})
+	}
+
+	p, err := Lookup(EmailMeta{Subject: "purchase", FromAddress: &mail.Address{Address: "alerts@notify.MyBank.com"}})
+	if err != nil || Name(p) != "mybank.purchase" {
+		t.Errorf("Lookup = %s, %v; want the parser for the sender's domain", Name(p), err)
+	}
+	if tried["otherbank.purchase"] != 0 {
+		t.Error("parser for another domain was tried")
+	}
+
+	if p := Find(EmailMeta{Subject: "purchase"}); p != nil {
+		t.Errorf("Find = %s; domain parsers should not match unknown senders", Name(p))
+	}
+}
+
+// countingParser records how often Match is called
+type countingParser struct {
+	Parser
+	tried map[string]int
+}
+
+func (c countingParser) Describe() Info { return c.Parser.(Describer).Describe() }
+
+func (c countingParser) Match(m EmailMeta) bool {
+	c.tried[Name(c.Parser)]++
+	return c.Parser.Match(m)
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	withRegistry(t)
+	Register(fakeParser{})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering the same id twice did not panic")
+		}
+	}()
+	Register(fakeParser{subject: "other"})
+}
+
+func TestRegistered(t *testing.T) {
+	withRegistry(t)
+	Register(described{info: Info{ID: "b.low"}})
+	Register(described{info: Info{ID: "a.high", Priority: 1, Domains: []string{"a.com"}}})
+
+	var ids []string
+	for _, info := range Registered() {
+		ids = append(ids, info.ID)
+	}
+	if strings.Join(ids, " ") != "a.high b.low" {
+		t.Errorf("Registered = %v; want find order", ids)
+	}
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // zone names resolve in containers without /usr/share/zoneinfo
+)
+
+var (
+	zonesMu   sync.RWMutex
+	bankZones = map[string]*time.Location{}
+)
+
+// RegisterTimezone sets the zone dates of a bank are read in when the user has none,
+// usually the zone of its head office. it panics on an unknown zone name, like Register
+// on a duplicate id
+func RegisterTimezone(bank, name string) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(fmt.Sprintf("parser: timezone of %q: %v", bank, err))
+	}
+
+	zonesMu.Lock()
+	defer zonesMu.Unlock()
+	bankZones[strings.ToLower(bank)] = loc
+}
+
+// DateLocation returns the zone dates in an email from bank are read in: the user's
+// zone, else the bank's, else UTC
+func DateLocation(m EmailMeta, bank string) *time.Location {
+	if m.Location != nil {
+		return m.Location
+	}
+
+	zonesMu.RLock()
+	defer zonesMu.RUnlock()
+	if loc, ok := bankZones[strings.ToLower(bank)]; ok {
+		return loc
+	}
+	return time.UTC
+}
+
+// ReceivedDate returns when the notification was sent, from the Date of the email or
+// else of its forward. a forwarded date written without a zone is read in loc
+func ReceivedDate(m EmailMeta, loc *time.Location) (time.Time, bool) {
+	if t, ok := parseMetaDate(m.Date); ok {
+		if m.FloatingDate {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+		}
+		return t, true
+	}
+	if m.Envelope != nil {
+		// the original date of a forward could not be read, the forward time is the next best thing
+		return parseMetaDate(m.Envelope.Date)
+	}
+	return time.Time{}, false
+}
+
+// clockLayouts are the times of day found in email bodies, e.g. "2:57 PM" or "14:57"
+var clockLayouts = []string{"3:04 PM", "3:04PM", "3:04:05 PM", "3:04:05PM", "15:04", "15:04:05"}
+
+// ClockPattern matches a time of day in any form ParseClock reads: "2:57 PM", "2:57pm",
+// "2:57 p.m." or "14:57:05". it has no capture group, wrap it in one like DatePattern
+const ClockPattern = `\b\d{1,2}:\d{2}(?::\d{2})?(?:\s?(?i:[ap]\.?m\b\.?))?`
+
+// ParseClock reads a time of day and returns it as a duration since midnight
+func ParseClock(raw string) (time.Duration, error) {
+	clean := strings.ToUpper(strings.Join(strings.Fields(strings.ReplaceAll(raw, ".", "")), " "))
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, clean); err == nil {
+			return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+		}
+	}
+	return 0, fmt.Errorf("unrecognized time of day %q", raw)
+}
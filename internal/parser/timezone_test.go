@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestBuildTransactionTimezone(t *testing.T) {
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vancouver, err := time.LoadLocation("America/Vancouver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterTimezone("tzbank", "America/Toronto")
+
+	tests := []struct {
+		name   string
+		meta   EmailMeta
+		bank   string
+		txtime string
+		want   time.Time
+	}{
+		{
+			// 21:30 in toronto is already the next day in utc
+			name: "evening purchase keeps the receive time",
+			meta: EmailMeta{Date: "2025-09-14T01:30:00Z", Location: toronto},
+			want: time.Date(2025, 9, 14, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "bank zone when the user has none",
+			meta: EmailMeta{Date: "2025-09-14T01:30:00Z"},
+			bank: "tzbank",
+			want: time.Date(2025, 9, 13, 21, 30, 0, 0, toronto),
+		},
+		{
+			name: "utc without either",
+			meta: EmailMeta{Date: "2025-09-14T01:30:00Z"},
+			want: time.Date(2025, 9, 13, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "earlier day is midnight where the user is",
+			meta: EmailMeta{Date: "2025-09-15T12:00:00Z", Location: vancouver},
+			bank: "tzbank",
+			want: time.Date(2025, 9, 13, 0, 0, 0, 0, vancouver),
+		},
+		{
+			name:   "time of day from the body",
+			meta:   EmailMeta{Date: "2025-09-15T12:00:00Z", Location: toronto},
+			txtime: "2:57 PM",
+			want:   time.Date(2025, 9, 13, 14, 57, 0, 0, toronto),
+		},
+		{
+			// a forward said "September 13th, 2025 at 9:15 PM" and was read in utc
+			name: "floating forward date is read where the user is",
+			meta: EmailMeta{Date: "2025-09-13T21:15:00Z", FloatingDate: true, Location: toronto},
+			want: time.Date(2025, 9, 13, 21, 15, 0, 0, toronto),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := Fields{
+				"amount": {Value: "$12.00"},
+				"txdate": {Value: "September 13, 2025"},
+				"txtime": {Value: tt.txtime},
+			}
+			txn, err := BuildTransaction(tt.meta, fields, tt.bank, "CAD", "out", "desc")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !txn.TxDate.Equal(tt.want) {
+				t.Errorf("TxDate = %v; want %v", txn.TxDate, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := map[string]time.Duration{
+		"2:57 PM":     14*time.Hour + 57*time.Minute,
+		"2:57pm":      14*time.Hour + 57*time.Minute,
+		"12:05 a.m.":  5 * time.Minute,
+		"11:54:30 PM": 23*time.Hour + 54*time.Minute + 30*time.Second,
+		"14:57":       14*time.Hour + 57*time.Minute,
+	}
+	clock := regexp.MustCompile(`^` + ClockPattern + `$`)
+	for in, want := range tests {
+		if got, err := ParseClock(in); err != nil || got != want {
+			t.Errorf("ParseClock(%q) = %v, %v; want %v", in, got, err, want)
+		}
+		if !clock.MatchString(in) {
+			t.Errorf("ClockPattern does not match %q", in)
+		}
+	}
+	if _, err := ParseClock("noon"); err == nil {
+		t.Error("ParseClock(noon) did not fail")
+	}
+}
@@ -1,16 +1,61 @@
 package parser
 
 import (
+	"fmt"
 	"net/mail"
 	"null-email-parser/internal/domain"
+	"null-email-parser/internal/email"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
 )
 
 // EmailMeta holds the minimal fields needed to pick and parse a message
 type EmailMeta struct {
 	ID      string
+	From    string
 	Subject string
-	Text    string
+	Text    string // normalized body, html rendered to text, see email.Normalize
+	RawText string // body as decoded, before normalization
 	Date    string // RFC3339 from Mailpit
+
+	// FloatingDate is true when Date came from a forward that gave no zone, its clock
+	// time should be read in the user's zone. see ReceivedDate
+	FloatingDate bool
+
+	// Location is the user's timezone, nil to use the bank's. see DateLocation
+	Location *time.Location
+
+	// HTML is the parsed html body, nil for text-only emails. see SelectText and LabelValue
+	HTML *html.Node
+
+	// SMTP envelope, as given to MAIL FROM and RCPT TO. not part of the headers
+	MailFrom string
+	RcptTo   []string
+
+	FromAddress *mail.Address   // parsed From, nil if missing or malformed
+	To          []*mail.Address // parsed To
+	ReplyTo     []*mail.Address // parsed Reply-To
+	MessageID   string          // Message-ID without the angle brackets
+	Headers     mail.Header     // all headers of the original message, encoded-words decoded
+
+	Parts       []email.Part       // every text/plain and text/html part
+	Attachments []email.Attachment // attachment metadata, call Open or Content for the data
+
+	// Envelope is set when the notification reached us forwarded, and describes
+	// the wrapping message. the fields above always describe the original
+	Envelope *Envelope
+}
+
+// Envelope holds the headers of the message that carried a forwarded notification
+type Envelope struct {
+	From    string
+	Subject string
+	Date    string // RFC3339
+	Headers mail.Header
 }
 
 // Parser defines a bank‐specific parser
@@ -19,20 +64,157 @@ type Parser interface {
 	Parse(meta EmailMeta) (*domain.Transaction, error)
 }
 
-func ToEmailMeta(id string, msg *mail.Message, decodedContent string) (EmailMeta, error) {
-	subject := msg.Header.Get("Subject")
+// MultiParser is a parser for emails describing several transactions, such as daily
+// digests, transfers between two accounts or a purchase with a separate fee
+type MultiParser interface {
+	Match(meta EmailMeta) bool
+	ParseAll(meta EmailMeta) ([]*domain.Transaction, error)
+}
 
-	var dateStr string
-	if parsedDate, err := msg.Header.Date(); err == nil {
-		dateStr = parsedDate.Format("2006-01-02T15:04:05Z07:00") // RFC3339
-	} else {
-		dateStr = msg.Header.Get("Date")
+// AsMulti adapts a single-result Parser to a MultiParser
+func AsMulti(p Parser) MultiParser {
+	if mp, ok := p.(MultiParser); ok {
+		return mp
 	}
+	return single{p}
+}
 
-	return EmailMeta{
+type single struct{ Parser }
+
+func (s single) ParseAll(meta EmailMeta) ([]*domain.Transaction, error) {
+	txn, err := s.Parse(meta)
+	if err != nil || txn == nil {
+		return nil, err
+	}
+	return []*domain.Transaction{txn}, nil
+}
+
+// ReadEmailMeta reads an email file, e.g. a fixture, as the handler would receive it.
+// the path is its ID
+func ReadEmailMeta(path string) (EmailMeta, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return EmailMeta{}, err
+	}
+	msg, err := email.Parse(raw)
+	if err != nil {
+		return EmailMeta{}, fmt.Errorf("%s: %w", path, err)
+	}
+	meta, err := ToEmailMeta(path, msg)
+	if err != nil {
+		return EmailMeta{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return meta, nil
+}
+
+func ToEmailMeta(id string, msg *email.Message) (EmailMeta, error) {
+	meta := EmailMeta{
 		ID:      id,
-		Subject: subject,
-		Text:    decodedContent,
-		Date:    dateStr,
-	}, nil
+		From:    msg.Header.Get("From"),
+		Subject: msg.Header.Get("Subject"),
+		Text:    email.Normalize(msg.Text),
+		RawText: msg.Text,
+		Date:    headerDate(msg.Header),
+
+		FloatingDate: msg.FloatingDate,
+
+		FromAddress: firstAddress(msg.Header.Get("From")),
+		To:          parseAddressList(msg.Header.Get("To")),
+		ReplyTo:     parseAddressList(msg.Header.Get("Reply-To")),
+		MessageID:   strings.Trim(msg.Header.Get("Message-Id"), "<> "),
+		Headers:     msg.Header,
+		Parts:       msg.Parts,
+		Attachments: msg.Attachments,
+	}
+
+	if src := htmlSource(msg); src != "" {
+		if doc, err := html.Parse(strings.NewReader(src)); err == nil {
+			meta.HTML = doc
+		}
+	}
+
+	if msg.Outer != nil {
+		env := msg.Envelope()
+		meta.Envelope = &Envelope{
+			From:    env.Header.Get("From"),
+			Subject: env.Header.Get("Subject"),
+			Date:    headerDate(env.Header),
+			Headers: env.Header,
+		}
+	}
+
+	return meta, nil
+}
+
+// htmlSource returns the html body of msg, which may have ended up as its text
+func htmlSource(msg *email.Message) string {
+	if msg.HTML != "" {
+		return msg.HTML
+	}
+	if email.LooksLikeHTML(msg.Text) {
+		return msg.Text
+	}
+	return ""
+}
+
+// angleAddr finds addresses in headers net/mail rejects, e.g. decoded display names
+// containing commas
+var angleAddr = regexp.MustCompile(`(?:"?([^"<,]*?)"?\s*)?<([^<>@\s]+@[^<>\s]+)>|([^\s<>,"]+@[^\s<>,"]+)`)
+
+// parseAddressList parses an address header, leniently falling back to anything
+// that looks like an address when the header is not RFC 5322 compliant
+func parseAddressList(raw string) []*mail.Address {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	if list, err := mail.ParseAddressList(raw); err == nil {
+		return list
+	}
+
+	var out []*mail.Address
+	for _, m := range angleAddr.FindAllStringSubmatch(raw, -1) {
+		if m[2] != "" {
+			out = append(out, &mail.Address{Name: strings.TrimSpace(m[1]), Address: m[2]})
+		} else {
+			out = append(out, &mail.Address{Address: m[3]})
+		}
+	}
+	return out
+}
+
+// firstAddress parses a single address header such as From. any text before the
+// angle-addr is the display name, commas included
+func firstAddress(raw string) *mail.Address {
+	if addr, err := mail.ParseAddress(raw); err == nil {
+		return addr
+	}
+	if name, rest, ok := strings.Cut(raw, "<"); ok && !strings.Contains(rest, "<") {
+		if addr, _, ok := strings.Cut(rest, ">"); ok && strings.Contains(addr, "@") {
+			return &mail.Address{Name: strings.Trim(strings.TrimSpace(name), `"`), Address: strings.TrimSpace(addr)}
+		}
+	}
+	if list := parseAddressList(raw); len(list) > 0 {
+		return list[0]
+	}
+	return nil
+}
+
+// SenderDomain returns the lowercased domain of the From address, "" if unknown
+func (m EmailMeta) SenderDomain() string {
+	if m.FromAddress == nil {
+		return ""
+	}
+	_, domain, ok := strings.Cut(m.FromAddress.Address, "@")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(domain)
+}
+
+// headerDate returns the Date header as RFC3339, or raw if it cannot be parsed
+func headerDate(h mail.Header) string {
+	if parsedDate, err := h.Date(); err == nil {
+		return parsedDate.Format("2006-01-02T15:04:05Z07:00") // RFC3339
+	}
+	return h.Get("Date")
 }
@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"testing"
+
+	"null-email-parser/internal/email"
+)
+
+func TestToEmailMetaHeaders(t *testing.T) {
+	raw := "Subject: You made a purchase.\r\n" +
+		"From: =?UTF-8?Q?RBC_Royal_Bank=2C_Alerts?= <Alerts@Notify.RBC.com>\r\n" +
+		"To: Me <me@example.com>, other@example.com\r\n" +
+		"Reply-To: <noreply@rbc.com>\r\n" +
+		"Message-ID: <abc123@rbc.com>\r\n" +
+		"List-Id: Alerts <alerts.rbc.com>\r\n" +
+		"\r\n" +
+		"a purchase of $1.77 was made\r\n"
+
+	msg, err := email.Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	meta, err := ToEmailMeta("id", msg)
+	if err != nil {
+		t.Fatalf("ToEmailMeta returned error: %v", err)
+	}
+
+	// the decoded display name has a comma, which net/mail rejects unquoted
+	if meta.FromAddress == nil || meta.FromAddress.Name != "RBC Royal Bank, Alerts" || meta.FromAddress.Address != "Alerts@Notify.RBC.com" {
+		t.Errorf("FromAddress = %+v", meta.FromAddress)
+	}
+	if got := meta.SenderDomain(); got != "notify.rbc.com" {
+		t.Errorf("SenderDomain() = %q", got)
+	}
+	if len(meta.To) != 2 || meta.To[1].Address != "other@example.com" {
+		t.Errorf("To = %+v", meta.To)
+	}
+	if len(meta.ReplyTo) != 1 || meta.ReplyTo[0].Address != "noreply@rbc.com" {
+		t.Errorf("ReplyTo = %+v", meta.ReplyTo)
+	}
+	if meta.MessageID != "abc123@rbc.com" {
+		t.Errorf("MessageID = %q", meta.MessageID)
+	}
+	if got := meta.Headers.Get("List-Id"); got != "Alerts <alerts.rbc.com>" {
+		t.Errorf("List-Id = %q", got)
+	}
+	if len(meta.Parts) != 1 || meta.Parts[0].ContentType != "text/plain" {
+		t.Errorf("Parts = %+v", meta.Parts)
+	}
+	if meta.HTML != nil {
+		t.Error("HTML should be nil for a text-only email")
+	}
+}
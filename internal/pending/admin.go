@@ -0,0 +1,195 @@
+package pending
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"null-email-parser/internal/api"
+	"null-email-parser/internal/domain"
+	pb "null-email-parser/internal/gen/null/v1"
+
+	"github.com/charmbracelet/log"
+)
+
+// Core is the part of the null-core client the admin api needs
+type Core interface {
+	GetAccounts(userID string) ([]*pb.Account, error)
+	CreateAccount(userID string, acc api.NewAccount) (*pb.Account, error)
+	CreateTransactions(userID string, txs []*domain.Transaction) error
+}
+
+// Admin serves the pending accounts over http:
+//
+//	GET    /pending[?user=uuid]     list the pending accounts
+//	GET    /pending/{id}            one pending account with its transactions
+//	POST   /pending/{id}/map        {"account_id": 12}, use an existing account from now on
+//	POST   /pending/{id}/approve    {"alias": "..."} optional, create the account
+//	DELETE /pending/{id}            drop the account and its transactions
+//
+// it does no authentication, see admin.RequireKey
+type Admin struct {
+	Store *Store
+	Core  Core
+	Log   *log.Logger
+
+	// Alias names a created account when the request does not, nil for the parsed name
+	Alias func(bank string, kind domain.AccountKind, name string) string
+}
+
+// Register adds the routes to mux
+func (a *Admin) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /pending", a.list)
+	mux.HandleFunc("GET /pending/{id}", a.get)
+	mux.HandleFunc("POST /pending/{id}/map", a.mapAccount)
+	mux.HandleFunc("POST /pending/{id}/approve", a.approve)
+	mux.HandleFunc("DELETE /pending/{id}", a.remove)
+}
+
+// summary is an entry without its transactions
+type summary struct {
+	ID           string    `json:"id"`
+	UserUUID     string    `json:"user_uuid"`
+	Account      Account   `json:"account"`
+	Since        time.Time `json:"since"`
+	Transactions int       `json:"transactions"`
+}
+
+func (a *Admin) list(w http.ResponseWriter, r *http.Request) {
+	out := []summary{}
+	for _, e := range a.Store.List(r.URL.Query().Get("user")) {
+		out = append(out, summary{ID: e.ID, UserUUID: e.UserUUID, Account: e.Account, Since: e.Since, Transactions: len(e.Transactions)})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (a *Admin) get(w http.ResponseWriter, r *http.Request) {
+	e, err := a.Store.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+func (a *Admin) mapAccount(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		AccountID int `json:"account_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.AccountID <= 0 {
+		http.Error(w, "account_id is required", http.StatusBadRequest)
+		return
+	}
+
+	e, err := a.Store.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// the mapping is remembered, so the account has to be the user's own
+	accounts, err := a.Core.GetAccounts(e.UserID)
+	if err != nil {
+		a.Log.Error("failed to fetch accounts", "id", e.ID, "user_uuid", e.UserUUID, "err", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !slices.ContainsFunc(accounts, func(acc *pb.Account) bool { return acc.GetId() == int64(req.AccountID) }) {
+		http.Error(w, "account_id is not an account of the user", http.StatusBadRequest)
+		return
+	}
+
+	if e, err = a.Store.Map(e.ID, req.AccountID); err != nil {
+		writeError(w, err)
+		return
+	}
+	a.release(w, e, req.AccountID)
+}
+
+func (a *Admin) approve(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Alias string `json:"alias"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	e, err := a.Store.Get(r.PathValue("id"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	// created by an earlier approve whose transactions failed, don't create it twice
+	accountID, ok := a.Store.Lookup(e.UserUUID, e.Account.Bank, e.Account.Name)
+	if !ok {
+		alias := req.Alias
+		if alias == "" && a.Alias != nil {
+			alias = a.Alias(e.Account.Bank, e.Account.Kind, e.Account.Name)
+		}
+		account, err := a.Core.CreateAccount(e.UserUUID, api.NewAccount{
+			Name:     e.Account.Name,
+			Bank:     e.Account.Bank,
+			Kind:     e.Account.Kind,
+			Currency: e.Account.Currency,
+			Alias:    alias,
+		})
+		if err != nil {
+			a.Log.Error("failed to create approved account", "id", e.ID, "user_uuid", e.UserUUID, "err", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		accountID = int(account.Id)
+	}
+
+	if e, err = a.Store.Map(e.ID, accountID); err != nil {
+		writeError(w, err)
+		return
+	}
+	a.release(w, e, accountID)
+}
+
+// release creates the held transactions of an entry mapped to accountID
+func (a *Admin) release(w http.ResponseWriter, e Entry, accountID int) {
+	if err := a.Core.CreateTransactions(e.UserID, e.Transactions); err != nil {
+		a.Log.Error("failed to create held transactions", "id", e.ID, "user_uuid", e.UserUUID, "account_id", accountID, "err", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if err := a.Store.Release(e.ID, len(e.Transactions)); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	a.Log.Info("released held transactions", "id", e.ID, "user_uuid", e.UserUUID, "bank", e.Account.Bank, "account", e.Account.Name, "account_id", accountID, "count", len(e.Transactions))
+	writeJSON(w, http.StatusOK, map[string]any{"account_id": accountID, "created": len(e.Transactions)})
+}
+
+func (a *Admin) remove(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := a.Store.Remove(id); err != nil {
+		writeError(w, err)
+		return
+	}
+	a.Log.Info("dropped pending account", "id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,135 @@
+package pending
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"null-email-parser/internal/api"
+	"null-email-parser/internal/domain"
+	pb "null-email-parser/internal/gen/null/v1"
+
+	"github.com/charmbracelet/log"
+)
+
+type fakeCore struct {
+	existing []*pb.Account // of "user-id"
+	accounts []api.NewAccount
+	created  map[int]int // transactions per account
+	fail     bool
+}
+
+func (c *fakeCore) GetAccounts(userID string) ([]*pb.Account, error) {
+	if userID != "user-id" {
+		return nil, nil
+	}
+	return c.existing, nil
+}
+
+func (c *fakeCore) CreateAccount(userID string, acc api.NewAccount) (*pb.Account, error) {
+	c.accounts = append(c.accounts, acc)
+	return &pb.Account{Id: int64(100 + len(c.accounts))}, nil
+}
+
+func (c *fakeCore) CreateTransactions(userID string, txs []*domain.Transaction) error {
+	if c.fail {
+		return errors.New("null-core is down")
+	}
+	if c.created == nil {
+		c.created = map[int]int{}
+	}
+	for _, tx := range txs {
+		c.created[tx.AccountID]++
+	}
+	return nil
+}
+
+func newAdmin(t *testing.T) (*Admin, *fakeCore, string) {
+	t.Helper()
+	s, _ := New("")
+	if _, err := s.Hold("user", "user-id", card, txn("12.00")); err != nil {
+		t.Fatal(err)
+	}
+	core := &fakeCore{existing: []*pb.Account{{Id: 7}}}
+	a := &Admin{Store: s, Core: core, Log: log.New(io.Discard)}
+	return a, core, ID("user", "rbc", "1001")
+}
+
+func do(a *Admin, method, path, body string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	a.Register(mux)
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestAdminList(t *testing.T) {
+	a, _, id := newAdmin(t)
+	w := do(a, "GET", "/pending?user=user", "")
+	var list []summary
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 1 || list[0].ID != id || list[0].Transactions != 1 || list[0].Account != card {
+		t.Errorf("GET /pending = %s, %v", w.Body, err)
+	}
+	if w := do(a, "GET", "/pending/nope", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET of an unknown id = %d; want 404", w.Code)
+	}
+}
+
+func TestAdminMap(t *testing.T) {
+	a, core, id := newAdmin(t)
+	if w := do(a, "POST", "/pending/"+id+"/map", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("map without an account = %d; want 400", w.Code)
+	}
+	if w := do(a, "POST", "/pending/"+id+"/map", `{"account_id": 8}`); w.Code != http.StatusBadRequest {
+		t.Errorf("map to another user's account = %d; want 400", w.Code)
+	}
+	if _, ok := a.Store.Lookup("user", "rbc", "1001"); ok {
+		t.Error("mapping to another user's account was remembered")
+	}
+
+	core.fail = true
+	if w := do(a, "POST", "/pending/"+id+"/map", `{"account_id": 7}`); w.Code != http.StatusBadGateway {
+		t.Errorf("map while null-core is down = %d; want 502", w.Code)
+	}
+	if _, err := a.Store.Get(id); err != nil {
+		t.Fatalf("transactions dropped after a failed map: %v", err)
+	}
+
+	core.fail = false
+	if w := do(a, "POST", "/pending/"+id+"/map", `{"account_id": 7}`); w.Code != http.StatusOK || core.created[7] != 1 {
+		t.Errorf("map = %d %s, created %v", w.Code, w.Body, core.created)
+	}
+	if accountID, ok := a.Store.Lookup("user", "rbc", "1001"); !ok || accountID != 7 || len(a.Store.List("")) != 0 {
+		t.Errorf("after map: Lookup() = %d, %v, pending %d", accountID, ok, len(a.Store.List("")))
+	}
+}
+
+func TestAdminApprove(t *testing.T) {
+	a, core, id := newAdmin(t)
+
+	core.fail = true
+	do(a, "POST", "/pending/"+id+"/approve", "")
+	core.fail = false
+	if w := do(a, "POST", "/pending/"+id+"/approve", `{"alias": "Travel Visa"}`); w.Code != http.StatusOK {
+		t.Fatalf("approve = %d %s", w.Code, w.Body)
+	}
+
+	// the retry reuses the account the failed attempt created
+	if len(core.accounts) != 1 || core.accounts[0].Kind != domain.CreditCard || core.accounts[0].Name != "1001" || core.created[101] != 1 {
+		t.Errorf("accounts = %+v, created %v", core.accounts, core.created)
+	}
+}
+
+func TestAdminDelete(t *testing.T) {
+	a, core, id := newAdmin(t)
+	if w := do(a, "DELETE", "/pending/"+id, ""); w.Code != http.StatusNoContent || len(a.Store.List("")) != 0 || len(core.created) != 0 {
+		t.Errorf("DELETE = %d, pending %d", w.Code, len(a.Store.List("")))
+	}
+	if w := do(a, "DELETE", "/pending/"+id, ""); w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE = %d; want 404", w.Code)
+	}
+}
@@ -0,0 +1,54 @@
+package pending
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook tells the user about a newly pending account by posting it as json to URL,
+// e.g. an ntfy topic or a chat integration
+type Webhook struct {
+	URL    string
+	Client *http.Client // a client with a 10s timeout when nil
+}
+
+// Notification is what a Webhook posts
+type Notification struct {
+	Message  string    `json:"message"`
+	ID       string    `json:"id"`
+	UserUUID string    `json:"user_uuid"`
+	Account  Account   `json:"account"`
+	Since    time.Time `json:"since"`
+}
+
+// Notify posts e, without its transactions
+func (w *Webhook) Notify(e Entry) error {
+	n := Notification{
+		Message:  fmt.Sprintf("transactions of an unknown %s account %s are waiting; map it to one of your accounts or approve creating it (id %s)", e.Account.Bank, e.Account.Name, e.ID),
+		ID:       e.ID,
+		UserUUID: e.UserUUID,
+		Account:  e.Account,
+		Since:    e.Since,
+	}
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	client := w.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to notify: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to notify: %s", resp.Status)
+	}
+	return nil
+}
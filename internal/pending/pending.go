@@ -0,0 +1,230 @@
+// Package pending parks the transactions of accounts null-core does not know, instead
+// of creating an account for every new account number a parser comes up with. the
+// user maps the parsed account to one of their accounts, which is remembered for the
+// emails to come, or approves creating it
+package pending
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"null-email-parser/internal/domain"
+)
+
+// ErrNotFound is returned for an id that is not pending
+var ErrNotFound = errors.New("no such pending account")
+
+// Account is an account as parsed from the emails
+type Account struct {
+	Bank     string             `json:"bank"`
+	Name     string             `json:"name"` // account number without the mask, e.g. "1001"
+	Kind     domain.AccountKind `json:"kind,omitempty"`
+	Currency string             `json:"currency,omitempty"`
+}
+
+// Entry is an unknown account of a user and the transactions held for it
+type Entry struct {
+	ID           string                `json:"id"`
+	UserUUID     string                `json:"user_uuid"`
+	UserID       string                `json:"user_id"` // null-core id the transactions are created for
+	Account      Account               `json:"account"`
+	Since        time.Time             `json:"since"`
+	Transactions []*domain.Transaction `json:"transactions"`
+}
+
+// state is what is saved across restarts
+type state struct {
+	Pending  map[string]*Entry `json:"pending"`
+	Mappings map[string]int    `json:"mappings"` // user, bank and account name to null-core account id
+}
+
+// Store keeps the pending accounts and the mappings the user made. its zero value is
+// not usable, see New
+type Store struct {
+	Now func() time.Time // clock, for tests
+
+	// OnHold is called when transactions are held for an account that was not pending
+	// yet, so the user can be told. it runs under the lock and must not call the store
+	OnHold func(e Entry)
+
+	mu    sync.Mutex
+	state state
+	path  string // where the state is saved, "" to keep it in memory
+}
+
+// metrics are published at /debug/vars when the metrics server runs
+var metrics = expvar.NewInt("pending_accounts")
+
+// New returns a store that saves its state to path, "" to keep it in memory. the
+// state saved by a previous run is loaded
+func New(path string) (*Store, error) {
+	s := &Store{
+		Now:   time.Now,
+		state: state{Pending: map[string]*Entry{}, Mappings: map[string]int{}},
+		path:  path,
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.state.Pending == nil {
+		s.state.Pending = map[string]*Entry{}
+	}
+	if s.state.Mappings == nil {
+		s.state.Mappings = map[string]int{}
+	}
+	metrics.Set(int64(len(s.state.Pending)))
+	return s, nil
+}
+
+// key identifies an account of a user, the way the handler matches account names
+func key(userUUID, bank, name string) string {
+	return fmt.Sprintf("%s/%s-%s", userUUID, strings.ToLower(bank), name)
+}
+
+// ID is the id of the entry of an account, the same for every email about it
+func ID(userUUID, bank, name string) string {
+	sum := sha256.Sum256([]byte(key(userUUID, bank, name)))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Lookup returns the account the user mapped a parsed account to
+func (s *Store) Lookup(userUUID, bank, name string) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.state.Mappings[key(userUUID, bank, name)]
+	return id, ok
+}
+
+// Hold parks a transaction of an unknown account and returns its entry
+func (s *Store) Hold(userUUID, userID string, acc Account, txn *domain.Transaction) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := ID(userUUID, acc.Bank, acc.Name)
+	e := s.state.Pending[id]
+	isNew := e == nil
+	if isNew {
+		e = &Entry{ID: id, UserUUID: userUUID, UserID: userID, Account: acc, Since: s.Now().UTC()}
+		s.state.Pending[id] = e
+		metrics.Set(int64(len(s.state.Pending)))
+	}
+	e.Transactions = append(e.Transactions, txn)
+
+	if isNew && s.OnHold != nil {
+		s.OnHold(*e)
+	}
+	return *e, s.save()
+}
+
+// List returns the pending accounts of a user, "" for every user, oldest first
+func (s *Store) List(userUUID string) []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []Entry
+	for _, id := range slices.Sorted(maps.Keys(s.state.Pending)) {
+		if e := s.state.Pending[id]; userUUID == "" || e.UserUUID == userUUID {
+			out = append(out, *e)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b Entry) int { return a.Since.Compare(b.Since) })
+	return out
+}
+
+// Get returns a pending account
+func (s *Store) Get(id string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.state.Pending[id]
+	if e == nil {
+		return Entry{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return *e, nil
+}
+
+// Map remembers that a pending account is the null-core account accountID and returns
+// the entry with its transactions pointed at it. the entry stays pending until it is
+// released, once the transactions are created
+func (s *Store) Map(id string, accountID int) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.state.Pending[id]
+	if e == nil {
+		return Entry{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	s.state.Mappings[key(e.UserUUID, e.Account.Bank, e.Account.Name)] = accountID
+	for _, txn := range e.Transactions {
+		txn.AccountID = accountID
+	}
+	return *e, s.save()
+}
+
+// Release drops the first n transactions of a pending account once they are created,
+// and the account when no transaction was held for it in the meantime
+func (s *Store) Release(id string, n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.state.Pending[id]
+	if e == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	e.Transactions = e.Transactions[min(n, len(e.Transactions)):]
+	if len(e.Transactions) == 0 {
+		delete(s.state.Pending, id)
+		metrics.Set(int64(len(s.state.Pending)))
+	}
+	return s.save()
+}
+
+// Remove drops a pending account and its transactions
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state.Pending[id] == nil {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	delete(s.state.Pending, id)
+	metrics.Set(int64(len(s.state.Pending)))
+	return s.save()
+}
+
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
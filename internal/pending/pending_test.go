@@ -0,0 +1,85 @@
+package pending
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"null-email-parser/internal/domain"
+)
+
+var card = Account{Bank: "rbc", Name: "1001", Kind: domain.CreditCard, Currency: "CAD"}
+
+func txn(amount string) *domain.Transaction {
+	return &domain.Transaction{TxBank: "rbc", TxAccount: "****1001", TxAmount: domain.MustDecimal(amount), TxCurrency: "CAD", TxDirection: domain.Out}
+}
+
+func TestHoldAndMap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.json")
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notified []Entry
+	s.OnHold = func(e Entry) { notified = append(notified, e) }
+
+	for _, amount := range []string{"12.00", "3.50"} {
+		if _, err := s.Hold("user", "user-id", card, txn(amount)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(notified) != 1 {
+		t.Errorf("OnHold called %d times; want once per account", len(notified))
+	}
+	if list := s.List("user"); len(list) != 1 || len(list[0].Transactions) != 2 || list[0].ID != ID("user", "RBC", "1001") {
+		t.Fatalf("List() = %+v", list)
+	}
+	if list := s.List("someone else"); len(list) != 0 {
+		t.Errorf("List() of another user = %+v", list)
+	}
+
+	// a restart keeps what is held
+	if s, err = New(path); err != nil {
+		t.Fatal(err)
+	}
+	id := ID("user", "rbc", "1001")
+	e, err := s.Map(id, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.UserID != "user-id" || len(e.Transactions) != 2 || e.Transactions[0].AccountID != 42 || e.Transactions[1].TxAmount != domain.MustDecimal("3.50") {
+		t.Errorf("Map() = %+v", e)
+	}
+	if err := s.Release(id, len(e.Transactions)); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err = New(path); err != nil {
+		t.Fatal(err)
+	}
+	if accountID, ok := s.Lookup("user", "RBC", "1001"); !ok || accountID != 42 {
+		t.Errorf("Lookup() = %d, %v; want the mapped account", accountID, ok)
+	}
+	if _, ok := s.Lookup("someone else", "rbc", "1001"); ok {
+		t.Error("the mapping of a user applies to another")
+	}
+	if _, err := s.Get(id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a released account = %v; want ErrNotFound", err)
+	}
+}
+
+// a transaction held while the others were being created stays pending
+func TestReleaseKeepsLaterTransactions(t *testing.T) {
+	s, _ := New("")
+	s.Hold("user", "user-id", card, txn("1"))
+	e, _ := s.Map(ID("user", "rbc", "1001"), 7)
+	s.Hold("user", "user-id", card, txn("2"))
+
+	if err := s.Release(e.ID, len(e.Transactions)); err != nil {
+		t.Fatal(err)
+	}
+	left, err := s.Get(e.ID)
+	if err != nil || len(left.Transactions) != 1 || left.Transactions[0].TxAmount != domain.MustDecimal("2") {
+		t.Errorf("Get() = %+v, %v; want the later transaction", left, err)
+	}
+}
@@ -0,0 +1,239 @@
+package script
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"null-email-parser/internal/domain"
+	"null-email-parser/internal/parser"
+
+	"go.starlark.net/starlark"
+)
+
+// builtins are the functions every script can call, on top of the starlark built-ins
+var builtins = starlark.StringDict{
+	"extract":     starlark.NewBuiltin("extract", extract),
+	"find":        starlark.NewBuiltin("find", find),
+	"find_all":    starlark.NewBuiltin("find_all", findAll),
+	"transaction": starlark.NewBuiltin("transaction", newTransaction),
+}
+
+const stateKey = "state"
+
+// state is the per-call context builtins need
+type state struct {
+	parser *Parser
+	meta   parser.EmailMeta
+	fields parser.Fields // everything extract found, for provenance
+}
+
+func stateOf(thread *starlark.Thread, fn *starlark.Builtin) (*state, error) {
+	st, _ := thread.Local(stateKey).(*state)
+	if st == nil {
+		return nil, fmt.Errorf("%s: can only be called from match or parse", fn.Name())
+	}
+	return st, nil
+}
+
+// extract(text, {key: regex}) returns a dict of the first capture group of each regex,
+// failing like parser.ExtractFields when a field other than "account" is missing
+func extract(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		text     string
+		patterns *starlark.Dict
+	)
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 2, &text, &patterns); err != nil {
+		return nil, err
+	}
+	st, err := stateOf(thread, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := make(map[string]*regexp.Regexp, patterns.Len())
+	for _, item := range patterns.Items() {
+		key, ok1 := starlark.AsString(item[0])
+		expr, ok2 := starlark.AsString(item[1])
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%s: patterns must map strings to strings", fn.Name())
+		}
+		re, err := compile(fn, expr)
+		if err != nil {
+			return nil, err
+		}
+		compiled[key] = re
+	}
+
+	fields, err := parser.ExtractFields(text, compiled)
+	if err != nil {
+		return nil, err
+	}
+
+	if st.fields == nil {
+		st.fields = parser.Fields{}
+	}
+	out := starlark.NewDict(len(fields))
+	for key, field := range fields {
+		st.fields[key] = field
+		if err := out.SetKey(starlark.String(key), starlark.String(field.Value)); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// find(regex, text) returns the first capture group of the first match, the whole
+// match if the regex has no group, or None
+func find(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var expr, text string
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 2, &expr, &text); err != nil {
+		return nil, err
+	}
+	re, err := compile(fn, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	m := re.FindStringSubmatch(text)
+	if m == nil {
+		return starlark.None, nil
+	}
+	return starlark.String(m[min(1, len(m)-1)]), nil
+}
+
+// find_all(regex, text) is find for every match
+func findAll(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var expr, text string
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 2, &expr, &text); err != nil {
+		return nil, err
+	}
+	re, err := compile(fn, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []starlark.Value
+	for _, m := range re.FindAllStringSubmatch(text, -1) {
+		out = append(out, starlark.String(m[min(1, len(m)-1)]))
+	}
+	return starlark.NewList(out), nil
+}
+
+func compile(fn *starlark.Builtin, expr string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid regex %q: %w", fn.Name(), expr, err)
+	}
+	return re, nil
+}
+
+// transaction is a parsed transaction returned by a script
+type transaction struct {
+	txn *domain.Transaction
+}
+
+func (t *transaction) String() string {
+	return fmt.Sprintf("transaction(%s %s %s %s)", t.txn.TxDirection, t.txn.TxCurrency, t.txn.TxAmount, t.txn.TxDate.Format("2006-01-02"))
+}
+func (t *transaction) Type() string          { return "transaction" }
+func (t *transaction) Freeze()               {}
+func (t *transaction) Truth() starlark.Bool  { return true }
+func (t *transaction) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable: transaction") }
+
+// transaction(amount, txdate, direction, account="", description="", bank=, currency=, txtime="", account_kind="", account_currency="", foreign_amount="", exchange_rate="")
+// builds a transaction with parser.BuildTransaction. bank and currency default to the
+// script globals. amounts and the rate may be strings or numbers
+func newTransaction(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	st, err := stateOf(thread, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		amount               starlark.Value
+		txdate, direction    string
+		account, description string
+		bank, currency       = st.parser.bank, st.parser.currency
+		txtime, accountKind  string
+		accountCurrency      string
+		foreign, rate        starlark.Value
+	)
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs,
+		"amount", &amount,
+		"txdate", &txdate,
+		"direction", &direction,
+		"account?", &account,
+		"description?", &description,
+		"bank?", &bank,
+		"currency?", &currency,
+		"txtime?", &txtime,
+		"account_kind?", &accountKind,
+		"account_currency?", &accountCurrency,
+		"foreign_amount?", &foreign,
+		"exchange_rate?", &rate,
+	); err != nil {
+		return nil, err
+	}
+
+	dir := domain.Direction(direction)
+	if dir != domain.In && dir != domain.Out {
+		return nil, fmt.Errorf("%s: direction must be %q or %q, got %q", fn.Name(), domain.In, domain.Out, direction)
+	}
+
+	rawAmount, err := numberArg(fn, "amount", amount)
+	if err != nil {
+		return nil, err
+	}
+	rawForeign, err := numberArg(fn, "foreign_amount", foreign)
+	if err != nil {
+		return nil, err
+	}
+	rawRate, err := numberArg(fn, "exchange_rate", rate)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := parser.Fields{
+		"amount":  st.field("amount", rawAmount),
+		"txdate":  st.field("txdate", txdate),
+		"account": st.field("account", account),
+		"txtime":  st.field("txtime", txtime),
+
+		"account_kind":     st.field("account_kind", accountKind),
+		"account_currency": st.field("account_currency", accountCurrency),
+		"foreign_amount":   st.field("foreign_amount", rawForeign),
+		"exchange_rate":    st.field("exchange_rate", rawRate),
+	}
+
+	txn, err := parser.BuildTransaction(st.meta, fields, bank, currency, dir, description)
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{txn: txn}, nil
+}
+
+// numberArg returns an amount or rate passed as a string or a number as text for
+// parser.BuildTransaction, "" when it was not passed
+func numberArg(fn *starlark.Builtin, name string, v starlark.Value) (string, error) {
+	switch v := v.(type) {
+	case nil, starlark.NoneType:
+		return "", nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Int:
+		return v.String(), nil
+	case starlark.Float:
+		return strconv.FormatFloat(float64(v), 'f', 9, 64), nil // the precision of domain.Decimal
+	}
+	return "", fmt.Errorf("%s: %s must be a string or a number, got %s", fn.Name(), name, v.Type())
+}
+
+// field returns what extract found for key when the script passed that value on,
+// otherwise a field without a known source
+func (st *state) field(key, value string) parser.Field {
+	if f, ok := st.fields[key]; ok && f.Value == value {
+		return f
+	}
+	return parser.Field{Value: value, Pattern: "script " + st.parser.name, Offset: -1}
+}
@@ -0,0 +1,124 @@
+package script
+
+import (
+	"net/mail"
+
+	"null-email-parser/internal/parser"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// newEmail exposes an EmailMeta to scripts as a read-only struct
+//
+//	email.subject, email.text, email.raw_text, email.date, email.id
+//	email.sender            From header as received
+//	email.from_address      address of From, "" if malformed
+//	email.sender_domain     lowercased domain of From
+//	email.to, email.reply_to, email.rcpt_to   tuples of addresses
+//	email.mail_from, email.message_id
+//	email.envelope          the forwarding message, None unless forwarded
+//	email.header(name)      first value of a header, "" if missing
+//	email.label(text)       value next to a label in the html, None if missing
+//	email.select(css)       text of the first element matching a selector, None if missing
+//	email.select_all(css)   texts of every element matching a selector
+func newEmail(m parser.EmailMeta) *starlarkstruct.Struct {
+	fromAddress := ""
+	if m.FromAddress != nil {
+		fromAddress = m.FromAddress.Address
+	}
+
+	envelope := starlark.Value(starlark.None)
+	if m.Envelope != nil {
+		envelope = starlarkstruct.FromStringDict(starlark.String("envelope"), starlark.StringDict{
+			"sender":  starlark.String(m.Envelope.From),
+			"subject": starlark.String(m.Envelope.Subject),
+			"date":    starlark.String(m.Envelope.Date),
+			"header":  headerFunc(m.Envelope.Headers),
+		})
+	}
+
+	rcptTo := make(starlark.Tuple, len(m.RcptTo))
+	for i, r := range m.RcptTo {
+		rcptTo[i] = starlark.String(r)
+	}
+
+	email := starlarkstruct.FromStringDict(starlark.String("email"), starlark.StringDict{
+		"id":            starlark.String(m.ID),
+		"sender":        starlark.String(m.From),
+		"from_address":  starlark.String(fromAddress),
+		"sender_domain": starlark.String(m.SenderDomain()),
+		"subject":       starlark.String(m.Subject),
+		"text":          starlark.String(m.Text),
+		"raw_text":      starlark.String(m.RawText),
+		"date":          starlark.String(m.Date),
+		"to":            addresses(m.To),
+		"reply_to":      addresses(m.ReplyTo),
+		"mail_from":     starlark.String(m.MailFrom),
+		"rcpt_to":       rcptTo,
+		"message_id":    starlark.String(m.MessageID),
+		"envelope":      envelope,
+
+		"header": headerFunc(m.Headers),
+		"label": starlark.NewBuiltin("label", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			var label string
+			if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &label); err != nil {
+				return nil, err
+			}
+			if value, ok := parser.LabelValue(m.HTML, label); ok {
+				return starlark.String(value), nil
+			}
+			return starlark.None, nil
+		}),
+		"select": starlark.NewBuiltin("select", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			texts, err := selectAll(m, fn, args, kwargs)
+			if err != nil || len(texts) == 0 {
+				return starlark.None, err
+			}
+			return starlark.String(texts[0]), nil
+		}),
+		"select_all": starlark.NewBuiltin("select_all", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+			texts, err := selectAll(m, fn, args, kwargs)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]starlark.Value, len(texts))
+			for i, t := range texts {
+				out[i] = starlark.String(t)
+			}
+			return starlark.NewList(out), nil
+		}),
+	})
+	email.Freeze()
+
+	return email
+}
+
+func selectAll(m parser.EmailMeta, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) ([]string, error) {
+	var selector string
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &selector); err != nil {
+		return nil, err
+	}
+	if m.HTML == nil {
+		return nil, nil
+	}
+	return parser.SelectAllText(m.HTML, selector)
+}
+
+func headerFunc(h mail.Header) *starlark.Builtin {
+	return starlark.NewBuiltin("header", func(_ *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var name string
+		if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &name); err != nil {
+			return nil, err
+		}
+		return starlark.String(h.Get(name)), nil
+	})
+}
+
+func addresses(list []*mail.Address) starlark.Tuple {
+	out := make(starlark.Tuple, len(list))
+	for i, a := range list {
+		out[i] = starlark.String(a.Address)
+	}
+	return out
+}
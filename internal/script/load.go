@@ -0,0 +1,75 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"null-email-parser/internal/parser"
+
+	"github.com/charmbracelet/log"
+)
+
+// SetName is the parser.RegisterSet name scripts are registered under
+const SetName = "script"
+
+// Extension of script files
+const Extension = ".star"
+
+// LoadDir loads every script in dir, see Load. scripts that fail to load are skipped
+// and reported in the returned error, the others are still returned
+func LoadDir(dir string, limits Limits, logger *log.Logger) ([]*Parser, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading parser directory: %w", err)
+	}
+
+	var (
+		parsers []*Parser
+		errs    []error
+		names   = map[string]string{}
+	)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), Extension) {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		p, err := Load(path, limits, logger)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if other, dup := names[p.Name()]; dup {
+			errs = append(errs, fmt.Errorf("%s: name %q is already used by %s", path, p.Name(), other))
+			continue
+		}
+		names[p.Name()] = path
+
+		parsers = append(parsers, p)
+	}
+
+	return parsers, errors.Join(errs...)
+}
+
+// Reload loads dir and replaces the scripts in the registry with its content. it
+// returns the number of scripts registered along with any load errors
+func Reload(dir string, limits Limits, logger *log.Logger) (int, error) {
+	if _, err := os.Stat(dir); err != nil {
+		// the directory itself is unreadable, keep what is registered
+		return 0, fmt.Errorf("reading parser directory: %w", err)
+	}
+
+	parsers, err := LoadDir(dir, limits, logger)
+
+	set := make([]parser.MultiParser, 0, len(parsers))
+	for _, p := range parsers {
+		set = append(set, p)
+	}
+	parser.RegisterSet(SetName, set)
+
+	return len(parsers), err
+}
@@ -0,0 +1,293 @@
+// Package script runs parsers written in Starlark, a small sandboxed dialect of python,
+// for banks whose emails need more logic than a declarative definition can express.
+// scripts cannot touch the filesystem or network and every call is bounded by Limits
+package script
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"null-email-parser/internal/domain"
+	"null-email-parser/internal/parser"
+
+	"github.com/charmbracelet/log"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Limits bound the work a script may do in one call
+type Limits struct {
+	Steps   uint64        // starlark execution steps, roughly one per bytecode instruction
+	Timeout time.Duration // wall clock time
+}
+
+// DefaultLimits are generous for parsing an email and stop runaway loops quickly
+var DefaultLimits = Limits{Steps: 1_000_000, Timeout: time.Second}
+
+// fileOptions are the dialect scripts are written in
+var fileOptions = &syntax.FileOptions{Set: true, While: true, TopLevelControl: true}
+
+// Parser is a parser.MultiParser backed by a script. a script defines
+//
+//	bank = "mybank"
+//	currency = "CAD"            # optional, default CAD
+//	name = "mybank-purchase"    # optional, default the file name
+//	version = 2                 # optional, bump when the script changes for a new template
+//	priority = 10               # optional, higher wins when several parsers match
+//	domains = ["mybank.com"]    # optional, only try the script for these senders
+//
+//	def match(email):
+//	    return "You made a purchase" in email.subject
+//
+//	def parse(email):
+//	    f = extract(email.text, {"amount": r'\$([0-9,]+\.\d{2})', "txdate": r'([A-Za-z]+ \d{1,2}, \d{4})'})
+//	    return transaction(amount = f["amount"], txdate = f["txdate"], direction = "out")
+//
+// parse returns a transaction, a list of them, or None when the email holds none
+type Parser struct {
+	name     string
+	file     string
+	bank     string
+	currency string
+	version  int
+	priority int
+	domains  []string
+	limits   Limits
+	log      *log.Logger
+
+	match, parse starlark.Callable
+}
+
+// Name returns the script name, used in logs and errors
+func (p *Parser) Name() string { return p.name }
+
+// Describe identifies the script in the registry
+func (p *Parser) Describe() parser.Info {
+	return parser.Info{ID: p.name, Bank: p.bank, Version: p.version, Priority: p.priority, Domains: p.domains}
+}
+
+// File returns the file the script was loaded from
+func (p *Parser) File() string { return p.file }
+
+// Load runs a script file and checks it defines a parser. failures of its match
+// function and what it prints are logged to logger, nil to discard them
+func Load(path string, limits Limits, logger *log.Logger) (*Parser, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = log.New(io.Discard)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	thread, stop := newThread(name, limits, logger, nil)
+	defer stop()
+
+	globals, err := starlark.ExecFileOptions(fileOptions, thread, path, src, builtins)
+	if err != nil {
+		return nil, loadError(path, err)
+	}
+	globals.Freeze() // functions are shared by concurrent calls
+
+	p := &Parser{name: name, file: path, currency: "CAD", limits: limits, log: logger}
+
+	var errs []error
+	stringGlobal := func(key string, dst *string, required bool) {
+		switch v := globals[key].(type) {
+		case nil:
+			if required {
+				errs = append(errs, fmt.Errorf("%s: %s is not set", path, key))
+			}
+		case starlark.String:
+			if v == "" {
+				errs = append(errs, fmt.Errorf("%s: %s is empty", path, key))
+				return
+			}
+			*dst = string(v)
+		default:
+			errs = append(errs, fmt.Errorf("%s: %s must be a string, got %s", path, key, v.Type()))
+		}
+	}
+	funcGlobal := func(key string) starlark.Callable {
+		fn, ok := globals[key].(*starlark.Function)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: def %s(email) is missing", path, key))
+			return nil
+		}
+		if fn.NumParams() != 1 {
+			errs = append(errs, fmt.Errorf("%s: %s must take exactly one parameter, the email", path, key))
+		}
+		return fn
+	}
+
+	intGlobal := func(key string, dst *int) {
+		switch v := globals[key].(type) {
+		case nil:
+		case starlark.Int:
+			n, ok := v.Int64()
+			if !ok || n != int64(int(n)) {
+				errs = append(errs, fmt.Errorf("%s: %s is out of range", path, key))
+				return
+			}
+			*dst = int(n)
+		default:
+			errs = append(errs, fmt.Errorf("%s: %s must be an int, got %s", path, key, v.Type()))
+		}
+	}
+
+	stringGlobal("name", &p.name, false)
+	stringGlobal("bank", &p.bank, true)
+	stringGlobal("currency", &p.currency, false)
+	p.currency = strings.ToUpper(p.currency)
+	intGlobal("version", &p.version)
+	intGlobal("priority", &p.priority)
+	switch v := globals["domains"].(type) {
+	case nil:
+	case *starlark.List:
+		for i := range v.Len() {
+			d, ok := starlark.AsString(v.Index(i))
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: domains must be a list of strings", path))
+				break
+			}
+			p.domains = append(p.domains, d)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s: domains must be a list of strings, got %s", path, v.Type()))
+	}
+	p.match = funcGlobal("match")
+	p.parse = funcGlobal("parse")
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return p, nil
+}
+
+// loadError adds the script location to an error from running its top level
+func loadError(path string, err error) error {
+	var serr syntax.Error
+	if errors.As(err, &serr) {
+		return err // already has the position
+	}
+
+	var eval *starlark.EvalError
+	if errors.As(err, &eval) {
+		if pos := position(eval.CallStack); pos != "" {
+			return fmt.Errorf("%s: %w", pos, err)
+		}
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+func (p *Parser) Match(m parser.EmailMeta) bool {
+	v, err := p.call(p.match, m)
+	if err != nil {
+		// a broken match function must not stop the other parsers from being tried
+		p.log.Warn("script match failed", "parser", p.name, "err", err)
+		return false
+	}
+	return bool(v.Truth())
+}
+
+func (p *Parser) ParseAll(m parser.EmailMeta) ([]*domain.Transaction, error) {
+	v, err := p.call(p.parse, m)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case *transaction:
+		return []*domain.Transaction{v.txn}, nil
+	case starlark.Iterable:
+		var txns []*domain.Transaction
+		iter := v.Iterate()
+		defer iter.Done()
+		var item starlark.Value
+		for iter.Next(&item) {
+			t, ok := item.(*transaction)
+			if !ok {
+				return nil, p.resultError(item)
+			}
+			txns = append(txns, t.txn)
+		}
+		return txns, nil
+	}
+
+	return nil, p.resultError(v)
+}
+
+func (p *Parser) resultError(v starlark.Value) error {
+	return &parser.ParseError{Parser: p.name, Err: fmt.Errorf("parse must return a transaction, a list of them or None, got %s", v.Type())}
+}
+
+// call runs fn with the email within the parser's limits
+func (p *Parser) call(fn starlark.Callable, m parser.EmailMeta) (starlark.Value, error) {
+	thread, stop := newThread(p.name, p.limits, p.log, &state{parser: p, meta: m})
+	defer stop()
+
+	v, err := starlark.Call(thread, fn, starlark.Tuple{newEmail(m)}, nil)
+	if err != nil {
+		return nil, p.callError(err)
+	}
+	return v, nil
+}
+
+// callError reports a script failure as a *parser.ParseError naming the script
+func (p *Parser) callError(err error) error {
+	var perr *parser.ParseError
+	if errors.As(err, &perr) {
+		if perr.Parser == "" {
+			perr.Parser = p.name
+		}
+		return perr
+	}
+
+	var eval *starlark.EvalError
+	if errors.As(err, &eval) {
+		if pos := position(eval.CallStack); pos != "" {
+			err = fmt.Errorf("%s: %w", pos, err)
+		}
+	}
+	return &parser.ParseError{Parser: p.name, Err: err}
+}
+
+// position returns the innermost script location of a call stack
+func position(stack starlark.CallStack) string {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].Pos.Line > 0 { // builtins have no line
+			return stack[i].Pos.String()
+		}
+	}
+	return ""
+}
+
+// newThread returns a thread enforcing limits and printing to logger. stop must be
+// called once it is done
+func newThread(name string, limits Limits, logger *log.Logger, st *state) (*starlark.Thread, func()) {
+	thread := &starlark.Thread{
+		Name: name,
+		Print: func(_ *starlark.Thread, msg string) {
+			logger.Debug("script print", "parser", name, "msg", msg)
+		},
+		// no Load, scripts cannot import anything
+	}
+	thread.SetMaxExecutionSteps(limits.Steps)
+	thread.SetLocal(stateKey, st)
+
+	if limits.Timeout <= 0 {
+		return thread, func() {}
+	}
+	timer := time.AfterFunc(limits.Timeout, func() {
+		thread.Cancel(fmt.Sprintf("timed out after %s", limits.Timeout))
+	})
+	return thread, func() { timer.Stop() }
+}
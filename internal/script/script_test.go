@@ -0,0 +1,299 @@
+package script
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"null-email-parser/internal/domain"
+	"null-email-parser/internal/parser"
+	"null-email-parser/internal/parser/parsertest"
+
+	"github.com/charmbracelet/log"
+)
+
+// writeScript writes src to a temporary .star file and loads it
+func writeScript(t *testing.T, src string, limits Limits) *Parser {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.star")
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(path, limits, nil)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	return p
+}
+
+func TestLoadDir(t *testing.T) {
+	parsers, err := LoadDir(filepath.Join("testdata", "valid"), DefaultLimits, nil)
+	if err != nil {
+		t.Fatalf("LoadDir returned error: %v", err)
+	}
+	if len(parsers) != 1 || parsers[0].Name() != "rbc-card" {
+		t.Fatalf("loaded %v; want the rbc-card script", parsers)
+	}
+	p := parsers[0]
+
+	meta := parsertest.LoadMeta(t, "../email/rbc/testdata/fw-you-made-a-purchase.decoded.eml")
+	if !p.Match(meta) {
+		t.Fatal("script did not match the purchase email")
+	}
+	if p.Match(parsertest.LoadMeta(t, "../email/rbc/testdata/deposit-notice.decoded.eml")) {
+		t.Error("script matched the deposit email")
+	}
+
+	txns, err := p.ParseAll(meta)
+	if err != nil {
+		t.Fatalf("ParseAll returned error: %v", err)
+	}
+	if len(txns) != 1 {
+		t.Fatalf("got %d transactions; want 1", len(txns))
+	}
+	txn := txns[0]
+	if txn.TxBank != "rbc" || txn.TxAccount != "************1001" || txn.TxAmount != domain.MustDecimal("39.50") ||
+		txn.TxCurrency != "CAD" || txn.TxDirection != domain.Out || txn.TxDesc != "Some No Frills 0000" {
+		t.Errorf("transaction = %+v", txn)
+	}
+	if src := txn.Provenance["amount"]; src.Offset < 0 || !strings.Contains(src.Pattern, `\$`) {
+		t.Errorf("amount provenance = %+v; want the extract regex and offset", src)
+	}
+
+	meta.Subject = "You received a refund"
+	if txns, err := p.ParseAll(meta); err != nil || txns[0].TxDirection != domain.In {
+		t.Errorf("refund parsed as %v, %v; want direction in", txns, err)
+	}
+}
+
+func TestLoadDirErrors(t *testing.T) {
+	parsers, err := LoadDir(filepath.Join("testdata", "invalid"), DefaultLimits, nil)
+	if len(parsers) != 0 {
+		t.Errorf("loaded %d invalid scripts", len(parsers))
+	}
+	if err == nil {
+		t.Fatal("LoadDir returned no error for invalid scripts")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		"incomplete.star: bank must be a string, got int",
+		"incomplete.star: match must take exactly one parameter",
+		"incomplete.star: def parse(email) is missing",
+		"syntax.star:4:1: got newline, want ':'",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error does not mention %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestSeveralTransactions(t *testing.T) {
+	p := writeScript(t, `
+bank = "mybank"
+
+def match(email):
+    return True
+
+def parse(email):
+    return [
+        transaction(amount = a, txdate = "June 1, 2025", direction = "out", description = "part %d" % i)
+        for i, a in enumerate(find_all(r'\$(\d+\.\d{2})', email.text))
+    ]
+`, DefaultLimits)
+
+	txns, err := p.ParseAll(parser.EmailMeta{Text: "split into $10.00 and $2.50"})
+	if err != nil {
+		t.Fatalf("ParseAll returned error: %v", err)
+	}
+	if len(txns) != 2 || txns[0].TxAmount != domain.MustDecimal("10") || txns[1].TxAmount != domain.MustDecimal("2.5") || txns[1].TxDesc != "part 1" {
+		t.Errorf("transactions = %+v", txns)
+	}
+	if src := txns[0].Provenance["amount"]; src.Pattern != "script test" || src.Offset != -1 {
+		t.Errorf("provenance = %+v; want the script without an offset", src)
+	}
+}
+
+func TestForeignAmount(t *testing.T) {
+	p := writeScript(t, `
+bank = "mybank"
+
+def match(email):
+    return True
+
+def parse(email):
+    f = extract(email.text, {"amount": r'charged (CAD [0-9.]+)', "foreign_amount": r'purchase of (USD [0-9.]+)', "exchange_rate": r'rate of ([0-9.]+)'})
+    return transaction(amount = f["amount"], txdate = "June 1, 2025", direction = "out",
+                       foreign_amount = f["foreign_amount"], exchange_rate = f["exchange_rate"])
+`, DefaultLimits)
+
+	text := "a purchase of USD 12.00 was charged CAD 16.52 at an exchange rate of 1.3767"
+	txns, err := p.ParseAll(parser.EmailMeta{Text: text})
+	if err != nil {
+		t.Fatalf("ParseAll returned error: %v", err)
+	}
+	txn := txns[0]
+	if txn.TxAmount != domain.MustDecimal("16.52") || txn.TxCurrency != "CAD" || txn.ForeignAmount == nil || *txn.ForeignAmount != domain.MustDecimal("12") ||
+		txn.ForeignCurrency == nil || *txn.ForeignCurrency != "USD" || txn.ExchangeRate == nil || *txn.ExchangeRate != domain.MustDecimal("1.3767") {
+		t.Errorf("transaction = %+v", txn)
+	}
+	if src := txn.Provenance["foreign_amount"]; src.Offset != strings.Index(text, "USD 12.00") {
+		t.Errorf("foreign_amount provenance = %+v; want the offset extract found", src)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	p := writeScript(t, `
+bank = "mybank"
+
+def match(email):
+    return True
+
+def parse(email):
+    if email.subject == "missing":
+        extract(email.text, {"amount": r'\$(\d+)'})
+    if email.subject == "fail":
+        fail("unexpected template")
+    return "not a transaction"
+`, DefaultLimits)
+
+	tests := []struct {
+		subject, field, want string
+	}{
+		{"missing", "amount", `parser test: field "amount" not found in text`},
+		{"fail", "", "test.star:11:13: fail: unexpected template"},
+		{"other", "", "parse must return a transaction, a list of them or None, got string"},
+	}
+	for _, tt := range tests {
+		_, err := p.ParseAll(parser.EmailMeta{Subject: tt.subject, Text: "no amount here"})
+
+		var perr *parser.ParseError
+		if !errors.As(err, &perr) || perr.Parser != "test" || perr.Field != tt.field {
+			t.Errorf("%s: err = %#v; want a ParseError for field %q", tt.subject, err, tt.field)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %q; want it to contain %q", tt.subject, err, tt.want)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	src := `
+bank = "mybank"
+
+def match(email):
+    while True:
+        pass
+
+def parse(email):
+    return None
+`
+
+	p := writeScript(t, src, Limits{Steps: 10_000, Timeout: time.Minute})
+	var logged bytes.Buffer
+	p.log = log.New(&logged)
+	if p.Match(parser.EmailMeta{}) {
+		t.Error("endless match returned true")
+	}
+	if !strings.Contains(logged.String(), "script match failed") {
+		t.Errorf("logged %q; want the match failure on the parser's logger", logged.String())
+	}
+	if _, err := p.call(p.match, parser.EmailMeta{}); err == nil || !strings.Contains(err.Error(), "too many steps") {
+		t.Errorf("err = %v; want the step limit", err)
+	}
+
+	p = writeScript(t, src, Limits{Timeout: 10 * time.Millisecond})
+	if _, err := p.call(p.match, parser.EmailMeta{}); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("err = %v; want the timeout", err)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "load.star")
+	if err := os.WriteFile(path, []byte(`load("os.star", "open")`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, DefaultLimits, nil); err == nil {
+		t.Error("script could load another module")
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("testdata", "valid", "rbc-purchase.star"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "card.star"), src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { parser.RegisterSet(SetName, nil) })
+
+	meta := parser.EmailMeta{Subject: "You received a refund", Text: "RBC Royal Bank"}
+
+	count, err := Reload(dir, DefaultLimits, nil)
+	if err != nil || count != 1 {
+		t.Fatalf("Reload = %d, %v; want 1 script", count, err)
+	}
+	if p := parser.Find(meta); parser.Name(p) != "rbc-card" {
+		t.Errorf("Find = %s; want the script", parser.Name(p))
+	}
+
+	if err := os.Remove(filepath.Join(dir, "card.star")); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := Reload(dir, DefaultLimits, nil); err != nil || count != 0 {
+		t.Fatalf("Reload = %d, %v; want 0 scripts", count, err)
+	}
+	if p := parser.Find(meta); p != nil {
+		t.Errorf("Find = %s; removed script is still registered", parser.Name(p))
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	p := writeScript(t, `
+bank = "mybank"
+version = 3
+priority = 10
+domains = ["mybank.com"]
+
+def match(email):
+    return True
+
+def parse(email):
+    return None
+`, DefaultLimits)
+
+	info := parser.Describe(p)
+	if info.ID != "test" || info.Bank != "mybank" || info.Version != 3 || info.Priority != 10 ||
+		len(info.Domains) != 1 || info.Domains[0] != "mybank.com" {
+		t.Errorf("Describe = %+v", info)
+	}
+}
+
+// a float amount is exact to the cent, 0.1 + 0.2 is 0.30 and not 0.30000000000000004
+func TestFloatAmount(t *testing.T) {
+	p := writeScript(t, `
+bank = "mybank"
+
+def match(email):
+    return True
+
+def parse(email):
+    return transaction(amount = 0.1 + 0.2, txdate = "June 1, 2025", direction = "out")
+`, DefaultLimits)
+
+	txns, err := p.ParseAll(parser.EmailMeta{})
+	if err != nil {
+		t.Fatalf("ParseAll returned error: %v", err)
+	}
+	if len(txns) != 1 || txns[0].TxAmount.String() != "0.30" {
+		t.Errorf("transactions = %+v; want one of 0.30", txns)
+	}
+}
@@ -1,15 +1,22 @@
 package smtp
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"null-email-parser/internal/api"
+	"null-email-parser/internal/capture"
 	"null-email-parser/internal/domain"
+	"null-email-parser/internal/drift"
 	"null-email-parser/internal/email"
 	_ "null-email-parser/internal/email/all"
 	pb "null-email-parser/internal/gen/null/v1"
+	"null-email-parser/internal/heuristic"
 	"null-email-parser/internal/parser"
+	"null-email-parser/internal/pending"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,6 +27,13 @@ type EmailHandler struct {
 	API           *api.Client
 	Log           *log.Logger
 	UnsafeSaveEML bool
+	Capture       *capture.Recorder // records unhandled emails as anonymized fixtures, nil to disable
+	Drift         *drift.Tracker    // notices template changes of the matched parsers, nil to disable
+
+	Fallback *heuristic.Extractor // guesses transactions of unmatched emails from allowlisted senders, nil to disable
+	Review   *heuristic.Review    // holds guessed transactions for review, nil to create them
+
+	Pending *pending.Store // holds transactions of unknown accounts until the user confirms them, nil to create the accounts
 }
 
 func NewEmailHandler(apiClient *api.Client, log *log.Logger, unsafeSaveEML bool) *EmailHandler {
@@ -48,45 +62,41 @@ func (h *EmailHandler) ProcessEmail(userUUID, from string, to []string, data []b
 	userID := user.Id
 	h.Log.Info("found user", "user_id", userID)
 
-	msg, decoded, err := email.ParseMessage(data)
+	msg, err := email.Parse(data)
 	if err != nil {
 		h.Log.Error("failed to parse email message", "user_uuid", userUUID, "from", from, "err", err)
 		return nil
 	}
 
-	meta, err := parser.ToEmailMeta(fmt.Sprintf("%s-%d", userUUID, len(data)), msg, decoded)
+	meta, err := parser.ToEmailMeta(fmt.Sprintf("%s-%d", userUUID, len(data)), msg)
 	if err != nil {
 		h.Log.Error("failed to parse email metadata", "user_uuid", userUUID, "from", from, "err", err)
 		return nil
 	}
+	meta.MailFrom, meta.RcptTo = from, to
+	meta.Location = h.userLocation(user)
 
-	prsr := parser.Find(meta)
-	if prsr == nil {
-		h.Log.Warn("no parser matched for email", "user_uuid", userUUID, "from", from, "subject", meta.Subject)
+	txns := h.transactions(userUUID, from, data, meta)
+	if len(txns) == 0 {
 		return nil
 	}
 
-	txn, err := prsr.Parse(meta)
-	if err != nil {
-		h.Log.Error("parser failed to extract transaction", "user_uuid", userUUID, "from", from, "subject", meta.Subject, "err", err)
-		return nil
-	}
-	if txn == nil {
-		return nil
+	for _, txn := range txns {
+		h.Log.Debug("parsed transaction",
+			"user_uuid", userUUID,
+			"email_id", txn.EmailID,
+			"tx_date", txn.TxDate,
+			"bank", txn.TxBank,
+			"account", txn.TxAccount,
+			"amount", txn.TxAmount,
+			"currency", txn.TxCurrency,
+			"direction", txn.TxDirection,
+			"description", txn.TxDesc,
+			"low_confidence", txn.LowConfidence,
+			"provenance", formatProvenance(txn.Provenance),
+		)
 	}
 
-	h.Log.Debug("parsed transaction",
-		"user_uuid", userUUID,
-		"email_id", txn.EmailID,
-		"tx_date", txn.TxDate,
-		"bank", txn.TxBank,
-		"account", txn.TxAccount,
-		"amount", txn.TxAmount,
-		"currency", txn.TxCurrency,
-		"direction", txn.TxDirection,
-		"description", txn.TxDesc,
-	)
-
 	accounts, err := h.API.GetAccounts(userID)
 	if err != nil {
 		h.Log.Error("failed to fetch accounts", "user_uuid", userUUID, "err", err)
@@ -101,21 +111,181 @@ func (h *EmailHandler) ProcessEmail(userUUID, from string, to []string, data []b
 		accountMap[fmt.Sprintf("%s-%s", strings.ToLower(acc.Bank), acc.Name)] = int(acc.Id)
 	}
 
-	if err := h.resolveAccount(userUUID, txn, accountMap, user); err != nil {
-		h.Log.Error("failed to resolve account", "user_uuid", userUUID, "from", from, "err", err)
+	// every account is resolved before anything is held, so an error can't leave
+	// part of the email in the pending store
+	var ready, unknown []*domain.Transaction
+	for _, txn := range txns {
+		known, err := h.resolveAccount(userUUID, txn, accountMap)
+		if err != nil {
+			h.Log.Error("failed to resolve account", "user_uuid", userUUID, "from", from, "err", err)
+			return nil
+		}
+		if known {
+			ready = append(ready, txn)
+		} else {
+			unknown = append(unknown, txn)
+		}
+	}
+	for _, txn := range unknown {
+		h.hold(userUUID, user.GetId(), txn)
+	}
+
+	txns = ready
+	if len(txns) == 0 {
 		return nil
 	}
 
-	if err := h.API.CreateTransaction(userID, txn); err != nil {
-		h.Log.Error("failed to create transaction", "user_uuid", userUUID, "from", from, "err", err)
+	if err := h.API.CreateTransactions(userID, txns); err != nil {
+		h.Log.Error("failed to create transaction", "user_uuid", userUUID, "from", from, "count", len(txns), "err", err)
 		return nil
 	}
 
-	h.Log.Info("transaction created successfully", "user_uuid", userUUID, "from", from, "bank", txn.TxBank, "amount", txn.TxAmount, "currency", txn.TxCurrency)
+	for _, txn := range txns {
+		h.Log.Info("transaction created successfully", "user_uuid", userUUID, "from", from, "bank", txn.TxBank, "amount", txn.TxAmount, "currency", txn.TxCurrency)
+	}
 
 	return nil
 }
 
+// userLocation returns the user's timezone, nil when unset or unknown so that the
+// bank's zone is used
+func (h *EmailHandler) userLocation(user *pb.User) *time.Location {
+	name := user.GetTimezone()
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		h.Log.Warn("unknown user timezone, using the bank's", "user_id", user.Id, "timezone", name, "err", err)
+		return nil
+	}
+	return loc
+}
+
+// transactions parses the email with the matching parser, or the heuristic extractor
+// when none matches. it returns nil when there is nothing to create
+func (h *EmailHandler) transactions(userUUID, from string, data []byte, meta parser.EmailMeta) []*domain.Transaction {
+	prsr, err := parser.Lookup(meta)
+	if err != nil {
+		h.Log.Warn("email matches several parsers", "user_uuid", userUUID, "from", from, "subject", meta.Subject, "err", err)
+		h.capture(data, capture.Ambiguous, prsr, err)
+	}
+	if prsr == nil {
+		h.Log.Warn("no parser matched for email", "user_uuid", userUUID, "from", from, "subject", meta.Subject)
+		h.capture(data, capture.Unmatched, nil, nil)
+		return h.fallback(userUUID, from, meta)
+	}
+
+	txns, err := prsr.ParseAll(meta)
+	h.observeDrift(meta, prsr, err)
+	if err != nil {
+		h.logParseError(userUUID, from, meta, prsr, err)
+		h.capture(data, capture.Failed, prsr, err)
+		return nil
+	}
+	return txns
+}
+
+// fallback runs the heuristic extractor on an unmatched email. its transaction is
+// held for review when a review directory is set, and created otherwise
+func (h *EmailHandler) fallback(userUUID, from string, meta parser.EmailMeta) []*domain.Transaction {
+	txn := h.Fallback.Extract(meta)
+	if txn == nil {
+		return nil
+	}
+
+	if h.Review != nil {
+		path, err := h.Review.Hold(userUUID, txn)
+		if err != nil {
+			h.Log.Error("failed to hold low confidence transaction", "user_uuid", userUUID, "from", from, "err", err)
+			return nil
+		}
+		h.Log.Info("low confidence transaction held for review", "user_uuid", userUUID, "from", from, "subject", meta.Subject, "path", path)
+		return nil
+	}
+
+	h.Log.Warn("creating low confidence transaction", "user_uuid", userUUID, "from", from, "subject", meta.Subject, "bank", txn.TxBank)
+	return []*domain.Transaction{txn}
+}
+
+// logParseError logs a parser failure, with field level details when the parser reports them
+func (h *EmailHandler) logParseError(userUUID, from string, meta parser.EmailMeta, prsr parser.MultiParser, err error) {
+	var perr *parser.ParseError
+	if !errors.As(err, &perr) {
+		h.Log.Error("parser failed to extract transaction", "user_uuid", userUUID, "from", from, "subject", meta.Subject, "parser", parser.Name(prsr), "err", err)
+		return
+	}
+
+	if perr.Parser == "" {
+		perr.Parser = parser.Name(prsr)
+	}
+
+	kv := []any{"user_uuid", userUUID, "from", from, "subject", meta.Subject, "err", err}
+	h.Log.Error("parser failed to extract transaction", append(kv, perr.LogValues()...)...)
+	h.Log.Debug("parse failure context", "parser", perr.Parser, "field", perr.Field, "value", parser.Redact(perr.Value), "excerpt", perr.Excerpt)
+}
+
+// capture records the email as an anonymized fixture when the outcome is captured
+func (h *EmailHandler) capture(data []byte, outcome capture.Outcome, prsr parser.MultiParser, err error) {
+	if !h.Capture.Wants(outcome) {
+		return
+	}
+
+	meta := capture.Meta{Outcome: outcome}
+	if prsr != nil {
+		info := parser.Describe(prsr)
+		meta.Parser, meta.Bank = info.ID, info.Bank
+	}
+	if err != nil {
+		meta.Error = parser.Redact(err.Error()) // conversion errors quote the value found
+	}
+
+	path, err := h.Capture.Record(data, meta)
+	switch {
+	case err != nil:
+		h.Log.Warn("failed to capture email", "outcome", outcome, "err", err)
+	case path == "":
+		h.Log.Debug("capture limit reached, email not captured", "outcome", outcome, "dir", h.Capture.Dir)
+	default:
+		h.Log.Info("captured email", "outcome", outcome, "path", path)
+	}
+}
+
+// observeDrift records the outcome of a parse and logs drift alerts as they are
+// raised and cleared
+func (h *EmailHandler) observeDrift(meta parser.EmailMeta, prsr parser.MultiParser, parseErr error) {
+	if h.Drift == nil {
+		return
+	}
+
+	alert, changed, err := h.Drift.Observe(parser.Describe(prsr), drift.Fingerprint(meta), parseErr)
+	if err != nil {
+		h.Log.Warn("failed to save drift state", "err", err)
+	}
+	switch {
+	case !changed:
+	case alert.Drifted():
+		h.Log.Warn("parser template drift detected", "parser", alert.Parser, "version", alert.Version, "reason", alert.Reason, "fingerprint", alert.Fingerprint, "subject", meta.Subject)
+	default:
+		h.Log.Info("parser drift cleared", "parser", alert.Parser, "version", alert.Version)
+	}
+}
+
+// formatProvenance renders field sources for logs, sorted by field, as the offset of
+// the match, -1 for a field not found, and the pattern, with the note when there is
+// one: `account@-1 "Account: (.+)" amount@123 "Amount: (.+)" txdate@140 "on (.+) at" (year inferred)`
+func formatProvenance(prov map[string]domain.FieldSource) string {
+	parts := make([]string, 0, len(prov))
+	for _, key := range slices.Sorted(maps.Keys(prov)) {
+		part := fmt.Sprintf("%s@%d %q", key, prov[key].Offset, prov[key].Pattern)
+		if note := prov[key].Note; note != "" {
+			part += " (" + note + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
 func (h *EmailHandler) saveEmailToFile(userUUID, from string, data []byte) error {
 	const debugDir = "debug_emails"
 	if err := os.MkdirAll(debugDir, 0755); err != nil {
@@ -151,12 +321,14 @@ func (h *EmailHandler) saveEmailToFile(userUUID, from string, data []byte) error
 	return nil
 }
 
-func (h *EmailHandler) resolveAccount(userUUID string, txn *domain.Transaction, accountMap map[string]int, user *pb.User) error {
+// resolveAccount sets the account of txn, creating the account when it is new. with
+// a pending store, new accounts are not created and known is false; see hold
+func (h *EmailHandler) resolveAccount(userUUID string, txn *domain.Transaction, accountMap map[string]int) (known bool, err error) {
 	noAccountParsed := txn.TxAccount == ""
 
 	if noAccountParsed {
 		h.Log.Warn("no account parsed from email; skipping transaction", "user_uuid", userUUID, "bank", txn.TxBank)
-		return nil
+		return true, nil
 	}
 
 	cleanAccount := strings.TrimLeft(txn.TxAccount, "*")
@@ -164,16 +336,64 @@ func (h *EmailHandler) resolveAccount(userUUID string, txn *domain.Transaction,
 
 	if existingAccountID, exists := accountMap[accountKey]; exists {
 		txn.AccountID = existingAccountID
-		return nil
+		return true, nil
 	}
 
-	account, err := h.API.CreateAccount(userUUID, cleanAccount, txn.TxBank)
+	if h.Pending != nil {
+		mappedAccountID, ok := h.Pending.Lookup(userUUID, txn.TxBank, cleanAccount)
+		txn.AccountID = mappedAccountID
+		return ok, nil
+	}
+
+	account, err := h.API.CreateAccount(userUUID, api.NewAccount{
+		Name:     cleanAccount,
+		Bank:     txn.TxBank,
+		Kind:     txn.AccountKind,
+		Currency: txn.AccountCurrency,
+		Alias:    AccountAlias(txn.TxBank, txn.AccountKind, cleanAccount),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create account for %s-%s: %w", txn.TxBank, cleanAccount, err)
+		return false, fmt.Errorf("failed to create account for %s-%s: %w", txn.TxBank, cleanAccount, err)
 	}
 
 	txn.AccountID = int(account.Id)
-	return nil
+	accountMap[accountKey] = txn.AccountID // later transactions of the same email reuse it
+	return true, nil
+}
+
+// hold parks a transaction of an unknown account in the pending store. a failure is
+// logged and the other transactions of the email are still held
+func (h *EmailHandler) hold(userUUID, userID string, txn *domain.Transaction) {
+	cleanAccount := strings.TrimLeft(txn.TxAccount, "*")
+	entry, err := h.Pending.Hold(userUUID, userID, pending.Account{
+		Name:     cleanAccount,
+		Bank:     txn.TxBank,
+		Kind:     txn.AccountKind,
+		Currency: txn.AccountCurrency,
+	}, txn)
+	if err != nil {
+		h.Log.Error("failed to hold transaction for unknown account", "user_uuid", userUUID, "bank", txn.TxBank, "account", cleanAccount, "amount", txn.TxAmount, "err", err)
+		return
+	}
+	h.Log.Info("transaction held for unknown account", "user_uuid", userUUID, "bank", txn.TxBank, "account", cleanAccount, "pending_id", entry.ID, "held", len(entry.Transactions))
+}
+
+// AccountAlias names a new account the way people do, "RBC Credit Card 1001" or
+// "RBC Savings" for an account the bank calls Savings
+func AccountAlias(bank string, kind domain.AccountKind, name string) string {
+	if len(bank) <= 4 {
+		bank = strings.ToUpper(bank) // rbc, td, bmo
+	} else {
+		bank = strings.ToUpper(bank[:1]) + bank[1:]
+	}
+	parts := []string{bank}
+	if label := kind.Label(); label != "" && !strings.EqualFold(label, name) {
+		parts = append(parts, label)
+	}
+	if strings.Trim(name, "0123456789") == "" && len(name) > 4 {
+		name = name[len(name)-4:] // the last digits are what statements and cards show
+	}
+	return strings.Join(append(parts, name), " ")
 }
 
 func sanitizeFilename(subject string) string {